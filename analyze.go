@@ -0,0 +1,220 @@
+package regexp2gen
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+
+	"github.com/dlclark/regexp2"
+	"github.com/dlclark/regexp2/syntax"
+)
+
+// Support is how well the generator handles a construct.
+type Support int
+
+const (
+	// Supported constructs are generated as they are.
+	Supported Support = iota
+	// Limited constructs are checked after they are generated and an attempt
+	// can fail on them, as a negative lookaround that the text after it matches.
+	Limited
+	// Unsatisfiable constructs can never match, as a backreference to a group
+	// that is not captured yet, \A after text or \z before text.
+	Unsatisfiable
+	// Unsupported constructs make every generation fail, as an instruction the
+	// generator has no code for. regexp2 compiles none of them at the moment.
+	Unsupported
+)
+
+func (s Support) String() string {
+	switch s {
+	case Supported:
+		return "supported"
+	case Limited:
+		return "limited"
+	case Unsatisfiable:
+		return "unsatisfiable"
+	case Unsupported:
+		return "unsupported"
+	}
+	return "unknown"
+}
+
+// seed of the trial generations, so that a pattern always gets the same report
+const analyzeSeed = 1
+
+// Construct is an anchor, boundary, backreference, lookaround, atomic group,
+// conditional or balancing group of a pattern. Index, Offset and Kind are the
+// same as in the errors of the generation.
+type Construct struct {
+	Index   int
+	Offset  int
+	Kind    string
+	Support Support
+	// why it is not supported, empty when it is
+	Reason string
+}
+
+// Report is what Analyze finds in a pattern.
+type Report struct {
+	Constructs []Construct
+	// estimated chance that Generate succeeds within the max attempts. Without
+	// trials it only says whether the match can get past the unsatisfiable and
+	// unsupported constructs: 0 when it can not, else 1, which limited constructs
+	// can make less in practice.
+	Probability float64
+	// how many trial generations Probability comes from, see WithTrials
+	Trials int
+}
+
+// AllSupported reports whether every construct is supported.
+func (r *Report) AllSupported() bool {
+	for _, c := range r.Constructs {
+		if c.Support != Supported {
+			return false
+		}
+	}
+	return true
+}
+
+// Analyze reports the constructs of the pattern re and how well they are supported,
+// from the compiled code alone. Only the regexp2 options, the max attempts,
+// the trials and the settings of the alphabet of opts are used.
+func Analyze(re string, opts ...Option) (*Report, error) {
+	return New(opts...).Analyze(re)
+}
+
+// Analyze is like the package Analyze with the options of the generator.
+// It does not use the random source of the generator.
+func (g *Generator) Analyze(re string) (*Report, error) {
+	reg, err := regexp2.Compile(re, g.options)
+	if err != nil {
+		return nil, err
+	}
+	p, err := compile(re, g.options)
+	if err != nil {
+		return nil, err
+	}
+	return g.analyze(p, reg.MatchString)
+}
+
+func (g *Generator) analyze(p *program, accept func(string) (bool, error)) (*Report, error) {
+	report := &Report{Constructs: []Construct{}}
+	blocked := map[int]bool{}
+	for i := 0; i < len(p.Codes); i += opcodeSize(syntax.InstOp(p.Codes[i])) {
+		support, reason, ok := p.support(i)
+		if !ok {
+			continue
+		}
+		if support >= Unsatisfiable {
+			blocked[i] = true
+		}
+		report.Constructs = append(report.Constructs, Construct{
+			Index:   i,
+			Offset:  p.offset(i),
+			Kind:    p.kind(i),
+			Support: support,
+			Reason:  reason,
+		})
+	}
+	// 另一个分支可以绕过不能匹配的地方，例如 \1(a)|b
+	if _, ok := p.minWidths(0, func(i int) bool { return blocked[i] })[p.matchEnd()]; !ok {
+		return report, nil
+	}
+	report.Probability = 1
+	if g.trials == 0 {
+		return report, nil
+	}
+
+	var s State
+	if g.state == nil {
+		s = *NewState(false, defaultLimit, nil, analyzeSeed)
+	} else {
+		g.mu.Lock()
+		s = *g.state
+		g.mu.Unlock()
+	}
+	// 试生成不影响生成器的随机数，也不输出跟踪
+	s.rand = rand.New(rand.NewSource(analyzeSeed))
+	s.tracer = nil
+
+	succeeded := 0
+	for trial := 0; trial < g.trials; trial++ {
+		_, err := g.generate(&s, p, accept)
+		var construct *UnsupportedConstructError
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.As(err, &construct), errors.Is(err, errGenerateFail):
+		default:
+			return nil, err
+		}
+	}
+	// 每次尝试都重新开始，只要有一次成功
+	once := float64(succeeded) / float64(g.trials)
+	report.Probability = 1 - math.Pow(1-once, float64(s.attempts))
+	report.Trials = g.trials
+	return report, nil
+}
+
+// support returns how well the instruction at index is supported,
+// false when it is not a construct Analyze reports.
+func (p *program) support(index int) (Support, string, bool) {
+	op := p.op(index)
+	if _, ok := anchorNames[op]; ok {
+		if p.unsatisfiable(index) {
+			if op == syntax.End {
+				return Unsatisfiable, "the match goes on with text after it", true
+			}
+			return Unsatisfiable, "the match has text before it", true
+		}
+		return Supported, "", true
+	}
+	switch op {
+	case syntax.Boundary, syntax.Nonboundary, syntax.ECMABoundary, syntax.NonECMABoundary:
+		return Supported, "", true
+	case syntax.Ref:
+		return p.refSupport(index)
+	case syntax.Setjump:
+		switch p.kind(index) {
+		case "negative lookaround":
+			return Limited, "checked on the finished text", true
+		case "conditional":
+			if p.op(index+1) == syntax.Setmark {
+				return Limited, "the no branch is checked on the finished text", true
+			}
+		}
+		return Supported, "", true
+	case syntax.Capturemark:
+		if p.Codes[index+2] != -1 {
+			return Limited, "depends on the captures of the balanced group", true
+		}
+	case syntax.Prune:
+		return Unsupported, "not generated by regexp2", true
+	}
+	return 0, "", false
+}
+
+// refSupport returns how well the backreference at index is supported.
+func (p *program) refSupport(index int) (Support, string, bool) {
+	group := p.Codes[index+1]
+	for i := 0; i < index; i += opcodeSize(syntax.InstOp(p.Codes[i])) {
+		if p.op(i) == syntax.Capturemark && p.Codes[i+1] == group {
+			return Supported, "", true
+		}
+	}
+	if p.options&regexp2.ECMAScript != 0 {
+		// 没有捕获的分组 ECMAScript 匹配空串
+		return Supported, "", true
+	}
+	// 循环的下一次可以用到上一次的捕获，例如 ((\3|b)\2(a)){2,}
+	for i := index; i < len(p.Codes); i += opcodeSize(syntax.InstOp(p.Codes[i])) {
+		switch p.op(i) {
+		case syntax.Branchmark, syntax.Lazybranchmark, syntax.Branchcount, syntax.Lazybranchcount:
+			if p.Codes[i+1] <= index {
+				return Limited, "refers to a group captured by an earlier iteration", true
+			}
+		}
+	}
+	return Unsatisfiable, "refers to a group that is not captured yet", true
+}
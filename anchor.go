@@ -0,0 +1,132 @@
+package regexp2gen
+
+import (
+	"github.com/dlclark/regexp2"
+	"github.com/dlclark/regexp2/syntax"
+)
+
+var anchorNames = map[syntax.InstOp]string{
+	syntax.Bol:       "^",
+	syntax.Eol:       "$",
+	syntax.Beginning: `\A`,
+	syntax.Start:     `\G`,
+	syntax.EndZ:      `\Z`,
+	syntax.End:       `\z`,
+}
+
+// anchor reports whether the anchor op at index can hold at the cursor,
+// closing the text or writing the newline it needs.
+func (r *runner) anchor(index int, op syntax.InstOp) bool {
+	buf := r.m.buf
+	if op == syntax.Start {
+		// \G 是开始匹配的位置，从右向左时是字符串的结尾
+		op = syntax.Beginning
+		if r.c.RightToLeft {
+			op = syntax.End
+		}
+	}
+
+	switch op {
+	case syntax.Beginning:
+		if buf.RemainingBack() > 0 {
+			r.edge = true
+			return false
+		}
+		buf.CloseStart(index)
+		return true
+
+	case syntax.End:
+		if buf.Remaining() > 0 {
+			r.edge = true
+			return false
+		}
+		buf.CloseEnd(index)
+		return true
+
+	case syntax.Bol:
+		if ch, ok := buf.Prev(); ok {
+			return ch == '\n'
+		}
+		if buf.StartClosed() {
+			return true
+		}
+		// 这里是字符串的开头，前面还有一行只在回溯时尝试
+		if r.choose(2, []float64{1, 0}) == 0 {
+			buf.CloseStart(index)
+			return true
+		}
+		for i := len(r.s.lineEnding) - 1; i >= 0; i-- {
+			if !buf.Prepend(r.s.lineEnding[i]) {
+				return false
+			}
+		}
+		return true
+
+	case syntax.Eol:
+		if ch, ok := buf.Next(); ok {
+			return ch == '\n'
+		}
+		if buf.EndClosed() {
+			return true
+		}
+		// $ 只在 \n 前面，后面还有一行只在回溯时尝试。\r\n 的换行里 \r 在 $ 前面
+		// 就不是行尾了，这里只能是单独的 \n
+		if r.choose(2, []float64{1, 0}) == 0 {
+			buf.CloseEnd(index)
+			return true
+		}
+		return buf.Append('\n')
+
+	case syntax.EndZ:
+		// RE2 和 ECMAScript 的 $ 只匹配字符串的结尾
+		if r.c.options&(regexp2.RE2|regexp2.ECMAScript) != 0 {
+			return r.anchor(index, syntax.End)
+		}
+		switch buf.Remaining() {
+		case 0:
+			if buf.EndClosed() {
+				return true
+			}
+			// 结尾可以再有一个换行，只在回溯时尝试
+			if r.choose(2, []float64{1, 0}) == 1 && !buf.Append('\n') {
+				return false
+			}
+			buf.CloseEnd(index)
+			return true
+		case 1:
+			if ch, _ := buf.Next(); ch != '\n' {
+				return false
+			}
+			buf.CloseEnd(index)
+			return true
+		}
+		r.edge = true
+		return false
+	}
+	return true
+}
+
+// outside writes a rune before or after the match, as a match can start and end
+// anywhere in the string. The match is written right to left for a RightToLeft
+// pattern, so its start is on the right.
+func (r *runner) outside(after bool) bool {
+	buf := r.m.buf
+	pos := buf.Start() - 1
+	if after != r.c.RightToLeft {
+		pos = buf.End()
+	}
+	chars := []rune{}
+	for _, ch := range r.s.chars {
+		if !r.excluded(pos, ch) {
+			chars = append(chars, ch)
+		}
+	}
+	if len(chars) == 0 {
+		return false
+	}
+	ch := r.s.randomRunes(chars, 1)[0]
+	if pos == buf.End() {
+		return buf.Append(ch)
+	}
+	return buf.Prepend(ch)
+}
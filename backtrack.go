@@ -0,0 +1,226 @@
+package regexp2gen
+
+import "errors"
+
+// errGenerateFail is returned when every choice point of an attempt is exhausted.
+var errGenerateFail = errors.New("generate string fail")
+
+// how many times a char choice is re-rolled before backtracking further
+const charRetries = 2
+
+// backtrack budget when matching a sub-pattern against generated text
+const matchBacktracks = 1024
+
+// setCount is a counter pushed by Setcount/Nullcount, min is the required repetitions,
+// target is the count the loop stops at, -1 until it is chosen.
+type setCount struct {
+	num    int
+	min    int
+	target int
+}
+
+// loopFrame counts the iterations left of a Branchmark loop.
+type loopFrame struct {
+	index  int
+	remain int
+}
+
+// prevent is a negative lookaround at pos, the code from start to stop must not match there.
+// back is set for a lookbehind, whose code matches the text before pos.
+type prevent struct {
+	// the Setjump or conditional Lazybranch it comes from
+	index int
+	pos   int
+	start int
+	stop  int
+	back  bool
+}
+
+// greedy is a greedy loop that has to stop at pos, the loop at index can not match the rune there.
+type greedy struct {
+	pos   int
+	index int
+}
+
+// machine is everything a walk over the code changes, so it can be saved and restored.
+type machine struct {
+	index int
+	buf   *buffer
+
+	// 记录 set count 的值
+	setCountNum []setCount
+	loops       []loopFrame
+	prevents    []prevent
+	boundaries  []boundary
+	greedies    []greedy
+}
+
+func newMachine(buf *buffer) *machine {
+	return &machine{
+		buf:         buf,
+		setCountNum: []setCount{},
+		loops:       []loopFrame{},
+	}
+}
+
+func (m *machine) clone() *machine {
+	return &machine{
+		index:       m.index,
+		buf:         m.buf.Clone(),
+		setCountNum: append([]setCount{}, m.setCountNum...),
+		loops:       append([]loopFrame{}, m.loops...),
+		prevents:    append([]prevent{}, m.prevents...),
+		boundaries:  append([]boundary{}, m.boundaries...),
+		greedies:    append([]greedy{}, m.greedies...),
+	}
+}
+
+// choicePoint saves the machine before a random decision,
+// options are the decisions not tried yet, retries the fresh re-rolls left.
+// prefix is the decisions the same op made before this one.
+type choicePoint struct {
+	m       *machine
+	prefix  []int
+	options []int
+	retries int
+}
+
+// runner drives one attempt: a walk that can go back to its last choice point.
+type runner struct {
+	s *State
+	c *program
+	m *machine
+
+	// index the walk stops at, -1 to run to the end of the code
+	stop int
+	// final check of the whole text, nil accepts everything
+	accept func(string) (bool, error)
+	// nil for sub matchers
+	tracer Tracer
+
+	points []*choicePoint
+	// decisions of the current op so far
+	decisions []int
+	// decisions replayed by the op of the resumed choice point
+	replay []int
+
+	backtracks int
+	budget     int
+	// op blamed for the deepest failure, -1 before any failure
+	failed int
+	// how far the deepest failure got: the index of the failing op,
+	// past the code for the checks of the finished text
+	depth int
+	// text when the deepest failure happened
+	partial string
+	// the deepest failure is the final check rejecting the text
+	rejected bool
+	// constraint that last excluded a rune of the current op, -1 for none
+	cause int
+	// text before the rune written outside the match, when padded
+	unpadded string
+	padded   bool
+	// Setjump of the atomic group matched as an ordinary group, -1 for none
+	loose int
+	// the last failure is at a closed start or end of the text,
+	// which other runes of the same length do not change
+	edge bool
+}
+
+func newRunner(s *State, c *program, buf *buffer) *runner {
+	return &runner{
+		s:      s,
+		c:      c,
+		m:      newMachine(buf),
+		stop:   -1,
+		budget: s.backtracks,
+		failed: -1,
+		depth:  -1,
+		cause:  -1,
+		loose:  -1,
+	}
+}
+
+// step is called before every op.
+func (r *runner) step() {
+	r.decisions = r.decisions[:0]
+	r.cause = -1
+}
+
+func (r *runner) replayed() (int, bool) {
+	if len(r.replay) == 0 {
+		return 0, false
+	}
+	v := r.replay[0]
+	r.replay = r.replay[1:]
+	r.decisions = append(r.decisions, v)
+	return v, true
+}
+
+// choose picks one of n options at random, the others are kept for backtracking.
+// weights may be nil for a uniform choice.
+// It must be called before the current op changes the machine.
+func (r *runner) choose(n int, weights []float64) int {
+	v, ok := r.replayed()
+	if !ok {
+		perm := r.s.randomOrder(n, weights)
+		if n > 1 {
+			r.points = append(r.points, &choicePoint{
+				m:       r.m.clone(),
+				prefix:  append([]int{}, r.decisions...),
+				options: perm[1:],
+			})
+		}
+		v = perm[0]
+		r.decisions = append(r.decisions, v)
+	}
+	r.trace(Event{Kind: EventChoice, Index: r.m.index, Choice: v, Choices: n})
+	return v
+}
+
+// reroll lets the current op run again with fresh random runes on backtrack.
+// It must be called before the current op changes the machine.
+func (r *runner) reroll() {
+	if _, ok := r.replayed(); ok {
+		return
+	}
+	r.points = append(r.points, &choicePoint{
+		m:       r.m.clone(),
+		prefix:  append([]int{}, r.decisions...),
+		retries: charRetries,
+	})
+	r.decisions = append(r.decisions, 0)
+}
+
+// backtrack restores the latest choice point that has something left to try.
+func (r *runner) backtrack() bool {
+	if r.backtracks >= r.budget {
+		return false
+	}
+	r.backtracks++
+	edge := r.edge
+	r.edge = false
+
+	for len(r.points) > 0 {
+		l := len(r.points)
+		cp := r.points[l-1]
+		var v int
+		if len(cp.options) > 0 {
+			v = cp.options[0]
+			cp.options = cp.options[1:]
+		} else if cp.retries > 0 && !edge {
+			cp.retries--
+		} else {
+			r.points = r.points[:l-1]
+			continue
+		}
+		if len(cp.options) == 0 && cp.retries == 0 {
+			r.points = r.points[:l-1]
+		}
+		r.replay = append(append([]int{}, cp.prefix...), v)
+		r.m = cp.m.clone()
+		r.trace(Event{Kind: EventBacktrack, Index: r.m.index})
+		return true
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package regexp2gen
+
+import (
+	"github.com/dlclark/regexp2/syntax"
+)
+
+/*
+boundary is a \b or \B at pos, it holds when the runes on both sides of pos
+are word runes or not as the op requires. The text outside of the generated
+runes counts as non-word, the same as the start and end of a string.
+
+	input: end sends endure lender
+	\bend\b -> (end)
+	\Bend\B -> s(end)s, l(end)er
+*/
+type boundary struct {
+	index int
+	pos   int
+	op    syntax.InstOp
+}
+
+// isWord reports whether ch is a word rune for the boundary op.
+func (b boundary) isWord(ch rune) bool {
+	if b.op == syntax.ECMABoundary || b.op == syntax.NonECMABoundary {
+		return syntax.IsECMAWordChar(ch)
+	}
+	return syntax.IsWordChar(ch)
+}
+
+// holds reports whether the boundary holds between the runes left and right.
+func (b boundary) holds(left, right bool) bool {
+	return (left != right) == (b.op == syntax.Boundary || b.op == syntax.ECMABoundary)
+}
+
+// side returns whether the rune at i is a word rune,
+// known is false when the text there is still undecided.
+func (b boundary) side(buf *buffer, i int) (word, known bool) {
+	if ch, ok := buf.At(i); ok {
+		return b.isWord(ch), true
+	}
+	// 不能再往外写的时候，外面就是字符串的边界
+	if i < buf.Start() {
+		return false, buf.StartClosed()
+	}
+	return false, buf.EndClosed()
+}
+
+// check reports whether the boundary holds in buf, known is false while
+// a side is undecided.
+func (b boundary) check(buf *buffer) (ok, known bool) {
+	left, lk := b.side(buf, b.pos-1)
+	right, rk := b.side(buf, b.pos)
+	if !lk || !rk {
+		return false, false
+	}
+	return b.holds(left, right), true
+}
+
+// allows reports whether ch can be written at pos without breaking the boundary.
+func (b boundary) allows(buf *buffer, pos int, ch rune) bool {
+	switch pos {
+	case b.pos:
+		if left, ok := b.side(buf, pos-1); ok {
+			return b.holds(left, b.isWord(ch))
+		}
+	case b.pos - 1:
+		if right, ok := b.side(buf, pos+1); ok {
+			return b.holds(b.isWord(ch), right)
+		}
+	}
+	return true
+}
+
+// settle decides the runes a boundary at the edge of the text still needs.
+// A word rune is written outside the match when the edge itself does not do,
+// as \B before a word rune at the start of the text.
+func (r *runner) settle() bool {
+	buf := r.m.buf
+	for _, b := range r.m.boundaries {
+		if _, known := b.check(buf); known {
+			continue
+		}
+		left, _ := b.side(buf, b.pos-1)
+		right, _ := b.side(buf, b.pos)
+		if b.holds(left, right) {
+			continue
+		}
+		words := []rune{}
+		for _, ch := range r.s.alphabet(ClassWord).chars {
+			if b.isWord(ch) {
+				words = append(words, ch)
+			}
+		}
+		if len(words) == 0 {
+			return false
+		}
+		ch := r.s.randomRunes(words, 1)[0]
+		if b.pos == buf.Start() {
+			if !buf.Prepend(ch) {
+				return false
+			}
+		} else if !buf.Append(ch) {
+			return false
+		}
+	}
+
+	frozen := buf.Freeze(buf.Pos())
+	for _, b := range r.m.boundaries {
+		if ok, _ := b.check(frozen); !ok {
+			r.fail(len(r.c.Codes), b.index)
+			return false
+		}
+	}
+	return true
+}
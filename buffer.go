@@ -2,14 +2,355 @@ package regexp2gen
 
 import (
 	"bytes"
+	"errors"
 )
 
+/*
+buffer is the generated text and a cursor into it.
+
+Text after the cursor was already decided by a lookahead, writing there only
+checks the decided rune. Right to left code writes before the cursor and can
+grow the text at its start, so positions are logical: text[0] is at base.
+A frozen buffer never grows, it is used to match sub-patterns against
+generated text.
+*/
+type buffer struct {
+	text []rune
+	// 每个字符是哪条字符指令随机选的，-1 表示已经有别的指令依赖它
+	owners []int
+	base   int
+	pos    int
+	frozen bool
+	// 锚点确定了字符串的开头或者结尾，不能再往外写
+	startClosed bool
+	endClosed   bool
+	// 确定开头和结尾的锚点
+	startAnchor int
+	endAnchor   int
+
+	// Setmark 保存的位置
+	marks []int
+	// group -> 捕获栈，每一项是 [start, end)，平衡组会出栈
+	groups map[int][][2]int
+}
+
+func newBuffer() *buffer {
+	return &buffer{
+		text:   []rune{},
+		owners: []int{},
+		marks:  []int{},
+		groups: make(map[int][][2]int),
+	}
+}
+
+func (b *buffer) Clone() *buffer {
+	c := &buffer{
+		text:   append([]rune{}, b.text...),
+		owners: append([]int{}, b.owners...),
+		base:   b.base,
+		pos:    b.pos,
+		frozen: b.frozen,
+
+		startClosed: b.startClosed,
+		endClosed:   b.endClosed,
+		startAnchor: b.startAnchor,
+		endAnchor:   b.endAnchor,
+
+		marks:  append([]int{}, b.marks...),
+		groups: make(map[int][][2]int, len(b.groups)),
+	}
+	for i, g := range b.groups {
+		c.groups[i] = append([][2]int{}, g...)
+	}
+	return c
+}
+
+// Freeze returns a frozen copy with the cursor at pos.
+func (b *buffer) Freeze(pos int) *buffer {
+	c := b.Clone()
+	c.frozen = true
+	c.pos = pos
+	c.marks = c.marks[:0]
+	return c
+}
+
+func (b *buffer) String() string {
+	return string(b.text)
+}
+
+func (b *buffer) Len() int {
+	return len(b.text)
+}
+
+func (b *buffer) Pos() int {
+	return b.pos
+}
+
+// Start returns the position of the first rune.
+func (b *buffer) Start() int {
+	return b.base
+}
+
+// End returns the position after the last rune.
+func (b *buffer) End() int {
+	return b.base + len(b.text)
+}
+
+// At returns the rune at position i.
+func (b *buffer) At(i int) (rune, bool) {
+	if i < b.base || i >= b.End() {
+		return 0, false
+	}
+	return b.text[i-b.base], true
+}
+
+// Next returns the rune already decided at the cursor.
+func (b *buffer) Next() (rune, bool) {
+	return b.At(b.pos)
+}
+
+// Prev returns the rune already decided before the cursor.
+func (b *buffer) Prev() (rune, bool) {
+	return b.At(b.pos - 1)
+}
+
+// Remaining returns how many decided runes follow the cursor.
+func (b *buffer) Remaining() int {
+	return b.End() - b.pos
+}
+
+// RemainingBack returns how many decided runes precede the cursor.
+func (b *buffer) RemainingBack() int {
+	return b.pos - b.base
+}
+
+// WriteRune writes r at the cursor, false if a different rune is already there.
+func (b *buffer) WriteRune(r rune) bool {
+	if next, ok := b.Next(); ok {
+		if next != r {
+			return false
+		}
+	} else if !b.Append(r) {
+		return false
+	}
+	b.pos++
+	return true
+}
+
+// WriteRuneBack writes r before the cursor and moves the cursor back.
+func (b *buffer) WriteRuneBack(r rune) bool {
+	if prev, ok := b.Prev(); ok {
+		if prev != r {
+			return false
+		}
+	} else if !b.Prepend(r) {
+		return false
+	}
+	b.pos--
+	return true
+}
+
+func (b *buffer) WriteAll(p []rune) bool {
+	for _, r := range p {
+		if !b.WriteRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteAllBack writes p so that it ends at the cursor and moves the cursor to its start.
+func (b *buffer) WriteAllBack(p []rune) bool {
+	for i := len(p) - 1; i >= 0; i-- {
+		if !b.WriteRuneBack(p[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Prepend adds r before the text, the cursor stays where it is.
+// It is false when the start of the text is closed.
+func (b *buffer) Prepend(r rune) bool {
+	if b.StartClosed() {
+		return false
+	}
+	b.text = append([]rune{r}, b.text...)
+	b.owners = append([]int{-1}, b.owners...)
+	b.base--
+	return true
+}
+
+// Append adds r after the text, the cursor stays where it is.
+// It is false when the end of the text is closed.
+func (b *buffer) Append(r rune) bool {
+	if b.EndClosed() {
+		return false
+	}
+	b.text = append(b.text, r)
+	b.owners = append(b.owners, -1)
+	return true
+}
+
+// Own records that the rune at pos was picked at random by the char instruction
+// at index, so a later write can still replace it. Index -1 keeps it as it is.
+func (b *buffer) Own(pos, index int) {
+	if _, ok := b.At(pos); ok {
+		b.owners[pos-b.base] = index
+	}
+}
+
+// Owner returns the char instruction that picked the rune at pos, -1 when
+// nothing can replace it.
+func (b *buffer) Owner(pos int) int {
+	if _, ok := b.At(pos); !ok || b.frozen {
+		return -1
+	}
+	return b.owners[pos-b.base]
+}
+
+// Replace changes the rune at pos, nothing can replace it afterwards.
+func (b *buffer) Replace(pos int, r rune) {
+	b.text[pos-b.base] = r
+	b.owners[pos-b.base] = -1
+}
+
+// StartClosed reports whether the text can not grow at its start.
+func (b *buffer) StartClosed() bool {
+	return b.frozen || b.startClosed
+}
+
+// EndClosed reports whether the text can not grow at its end.
+func (b *buffer) EndClosed() bool {
+	return b.frozen || b.endClosed
+}
+
+// CloseStart makes the current start the start of the string, by the anchor at index.
+func (b *buffer) CloseStart(index int) {
+	b.startClosed, b.startAnchor = true, index
+}
+
+// CloseEnd makes the current end the end of the string, by the anchor at index.
+func (b *buffer) CloseEnd(index int) {
+	b.endClosed, b.endAnchor = true, index
+}
+
+// Anchor returns the anchor that closed the start or the end of the text,
+// -1 when it is open.
+func (b *buffer) Anchor(start bool) int {
+	switch {
+	case start && b.startClosed:
+		return b.startAnchor
+	case !start && b.endClosed:
+		return b.endAnchor
+	}
+	return -1
+}
+
+// push
+func (b *buffer) Setmark() {
+	b.marks = append(b.marks, b.pos)
+}
+
+// pop
+func (b *buffer) Backmark(capture bool, index int) error {
+	if !capture {
+		index = -1
+	}
+	_, err := b.Capture(index, -1)
+	return err
+}
+
+/*
+Capture pops the latest mark and captures the text from it to the cursor
+into group index, as Capturemark(index, uncapture) does. Index is -1 to capture nothing.
+
+A balancing group (?<index-uncapture>...) also pops the last capture of uncapture,
+it is false when uncapture has no capture. Index then gets the text between
+the two captures.
+*/
+func (b *buffer) Capture(index, uncapture int) (bool, error) {
+	l := len(b.marks)
+	if l == 0 {
+		return false, errors.New("backmark without mark")
+	}
+	start, end := b.marks[l-1], b.pos
+	if end < start {
+		// 从右向左匹配
+		start, end = end, start
+	}
+
+	if uncapture != -1 {
+		stack := b.groups[uncapture]
+		if len(stack) == 0 {
+			return false, nil
+		}
+		last := stack[len(stack)-1]
+		b.groups[uncapture] = stack[:len(stack)-1]
+
+		// 取两个捕获中间的部分
+		if start >= last[1] {
+			start, end = last[1], start
+		} else if end <= last[0] {
+			start = last[0]
+		} else {
+			if end > last[1] {
+				end = last[1]
+			}
+			if last[0] > start {
+				start = last[0]
+			}
+		}
+	}
+
+	b.marks = b.marks[:l-1]
+	if index != -1 {
+		b.groups[index] = append(b.groups[index], [2]int{start, end})
+	}
+	return true, nil
+}
+
+// Captures returns how many captures of group index are on its stack.
+func (b *buffer) Captures(index int) int {
+	return len(b.groups[index])
+}
+
+// Getmark pops the latest mark and moves the cursor back to it.
+func (b *buffer) Getmark() error {
+	l := len(b.marks)
+	if l == 0 {
+		return errors.New("getmark without mark")
+	}
+	b.pos = b.marks[l-1]
+	b.marks = b.marks[:l-1]
+	return nil
+}
+
+// Group returns the text last captured by group index.
+func (b *buffer) Group(index int) ([]rune, bool) {
+	stack := b.groups[index]
+	if len(stack) == 0 {
+		return nil, false
+	}
+	g := stack[len(stack)-1]
+	return b.text[g[0]-b.base : g[1]-b.base], true
+}
+
+/*
+Buffer is the text buffer of the first versions of the package, kept so that
+code using it still builds. The generator no longer writes through it.
+
+Deprecated: Buffer is not used by Generator and will be removed.
+*/
 type Buffer struct {
 	*bytes.Buffer
 	buffers []*bytes.Buffer
 	marks   map[int]*bytes.Buffer
 }
 
+// NewBuffer creates an empty Buffer.
+//
+// Deprecated: see Buffer.
 func NewBuffer() *Buffer {
 	return &Buffer{
 		Buffer:  &bytes.Buffer{},
@@ -0,0 +1,409 @@
+package regexp2gen
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/dlclark/regexp2/syntax"
+)
+
+// runeRange is the runes from first to last, both included.
+type runeRange struct {
+	first rune
+	last  rune
+}
+
+// runeRanges is a set of runes as sorted, disjoint and not adjacent ranges.
+type runeRanges []runeRange
+
+// how many runes a char instruction samples from its set when the alphabet has none
+const setSamples = 8
+
+// sets with more ranges than this add no literal runes, as \d
+const literalRanges = 8
+
+// 代理区的字符不能单独出现在字符串里
+var surrogates = runeRanges{{0xd800, 0xdfff}}
+
+// newRuneRanges sorts and merges rs.
+func newRuneRanges(rs []runeRange) runeRanges {
+	sorted := append([]runeRange{}, rs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].first < sorted[j].first
+	})
+	result := runeRanges{}
+	for _, r := range sorted {
+		if l := len(result); l > 0 && r.first <= result[l-1].last+1 {
+			if r.last > result[l-1].last {
+				result[l-1].last = r.last
+			}
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// tableRanges returns the runes of a unicode table.
+func tableRanges(table *unicode.RangeTable) runeRanges {
+	rs := []runeRange{}
+	add := func(lo, hi, stride rune) {
+		if stride == 1 {
+			rs = append(rs, runeRange{lo, hi})
+			return
+		}
+		for ch := lo; ch <= hi; ch += stride {
+			rs = append(rs, runeRange{ch, ch})
+		}
+	}
+	for _, r := range table.R16 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range table.R32 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	return newRuneRanges(rs)
+}
+
+// graphic is the assigned runes that print something, as unicode.IsGraphic.
+var graphic = func() runeRanges {
+	rs := runeRanges{}
+	for _, t := range unicode.GraphicRanges {
+		rs = rs.union(tableRanges(t))
+	}
+	return rs
+}()
+
+func (rs runeRanges) union(other runeRanges) runeRanges {
+	return newRuneRanges(append(append([]runeRange{}, rs...), other...))
+}
+
+// complement returns every rune not in rs.
+func (rs runeRanges) complement() runeRanges {
+	result := runeRanges{}
+	next := rune(0)
+	for _, r := range rs {
+		if r.first > next {
+			result = append(result, runeRange{next, r.first - 1})
+		}
+		next = r.last + 1
+	}
+	if next <= unicode.MaxRune {
+		result = append(result, runeRange{next, unicode.MaxRune})
+	}
+	return result
+}
+
+// subtract returns the runes of rs not in other.
+func (rs runeRanges) subtract(other runeRanges) runeRanges {
+	result := runeRanges{}
+	rest := other.complement()
+	i, j := 0, 0
+	for i < len(rs) && j < len(rest) {
+		first, last := rs[i].first, rs[i].last
+		if rest[j].first > first {
+			first = rest[j].first
+		}
+		if rest[j].last < last {
+			last = rest[j].last
+		}
+		if first <= last {
+			result = append(result, runeRange{first, last})
+		}
+		if rs[i].last < rest[j].last {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+func (rs runeRanges) intersect(other runeRanges) runeRanges {
+	return rs.subtract(other.complement())
+}
+
+// size returns how many runes are in rs.
+func (rs runeRanges) size() int64 {
+	n := int64(0)
+	for _, r := range rs {
+		n += int64(r.last-r.first) + 1
+	}
+	return n
+}
+
+// random returns a rune of rs, every rune is as likely.
+func (rs runeRanges) random(rnd *rand.Rand) (rune, bool) {
+	n := rs.size()
+	if n == 0 {
+		return 0, false
+	}
+	k := rnd.Int63n(n)
+	for _, r := range rs {
+		size := int64(r.last-r.first) + 1
+		if k < size {
+			return r.first + rune(k), true
+		}
+		k -= size
+	}
+	return 0, false
+}
+
+// 按字符集的描述缓存，满了就清空，让缓存不会一直变大
+const setCacheSize = 256
+
+var setCache = struct {
+	sync.Mutex
+	m map[string]runeRanges
+}{m: map[string]runeRanges{}}
+
+// members returns every rune of set that can be in a string. syntax.CharSet keeps
+// its ranges and categories unexported, so they are read back from set.String()
+// and the categories taken from the unicode tables. When the description can't
+// be read back, CharIn is scanned over every rune instead.
+func members(set *syntax.CharSet) runeRanges {
+	key := set.String()
+	setCache.Lock()
+	rs, ok := setCache.m[key]
+	setCache.Unlock()
+	if ok {
+		return rs
+	}
+	rs, rest, ok := parseSet([]rune(key), true)
+	if ok && len(rest) == 0 {
+		rs = rs.subtract(surrogates)
+	}
+	if !ok || len(rest) > 0 || !sameMembers(set, rs) {
+		rs = scanMembers(set)
+	}
+	setCache.Lock()
+	if len(setCache.m) >= setCacheSize {
+		setCache.m = map[string]runeRanges{}
+	}
+	setCache.m[key] = rs
+	setCache.Unlock()
+	return rs
+}
+
+// scanMembers returns the runes of set by calling CharIn on every rune.
+func scanMembers(set *syntax.CharSet) runeRanges {
+	rs := runeRanges{}
+	for ch := rune(0); ch <= unicode.MaxRune; ch++ {
+		if ch == surrogates[0].first {
+			ch = surrogates[0].last + 1
+		}
+		if !set.CharIn(ch) {
+			continue
+		}
+		if l := len(rs); l > 0 && rs[l-1].last == ch-1 {
+			rs[l-1].last = ch
+		} else {
+			rs = append(rs, runeRange{ch, ch})
+		}
+	}
+	return rs
+}
+
+// sameMembers checks the ends of every range of rs against set, the description
+// is not always read back right, as a '-' or a \u escape followed by hex digits.
+func sameMembers(set *syntax.CharSet, rs runeRanges) bool {
+	outside := func(ch rune) bool {
+		return ch < 0 || ch > unicode.MaxRune || (ch >= surrogates[0].first && ch <= surrogates[0].last) || !set.CharIn(ch)
+	}
+	for _, r := range rs {
+		if !set.CharIn(r.first) || !set.CharIn(r.last) || !outside(r.first-1) || !outside(r.last+1) {
+			return false
+		}
+	}
+	return true
+}
+
+// setToken is a rune in the ranges of a set description, dash is an unescaped '-'.
+type setToken struct {
+	r    rune
+	dash bool
+}
+
+// parseSet reads a description written by syntax.CharSet.String back to its runes,
+// it returns what is left after the closing ']'. Without categories, a set with
+// categories is not read, as they take the unicode tables.
+func parseSet(s []rune, categories bool) (runeRanges, []rune, bool) {
+	if len(s) == 0 || s[0] != '[' {
+		return nil, nil, false
+	}
+	s = s[1:]
+	negate := len(s) > 0 && s[0] == '^'
+	if negate {
+		s = s[1:]
+	}
+
+	// 先是范围里的字符
+	tokens := []setToken{}
+	for len(s) > 0 && s[0] != ']' {
+		if s[0] == '-' && len(s) > 1 && s[1] == '[' {
+			break
+		}
+		if s[0] != '\\' {
+			tokens = append(tokens, setToken{s[0], s[0] == '-'})
+			s = s[1:]
+			continue
+		}
+		if len(s) < 2 || strings.ContainsRune("sSwWpP", s[1]) {
+			break
+		}
+		ch, n, ok := unescapeRune(s)
+		if !ok {
+			return nil, nil, false
+		}
+		tokens = append(tokens, setToken{r: ch})
+		s = s[n:]
+	}
+	rs := []runeRange{}
+	for i := 0; i < len(tokens); {
+		first := tokens[i].r
+		switch {
+		case i+2 < len(tokens) && tokens[i+1].dash && tokens[i+2].r > first+1:
+			rs = append(rs, runeRange{first, tokens[i+2].r})
+			i += 3
+		case i+1 < len(tokens) && tokens[i+1].r == first+1:
+			rs = append(rs, runeRange{first, first + 1})
+			i += 2
+		default:
+			rs = append(rs, runeRange{first, first})
+			i++
+		}
+	}
+	result := newRuneRanges(rs)
+
+	// 再是分类，和 CharIn 一样按顺序看：字符在正的分类里就算进来，
+	// 遇到取反的分类就由它决定，后面的分类不再看
+	undecided := result.complement()
+	for len(s) > 1 && s[0] == '\\' {
+		if !categories {
+			return nil, nil, false
+		}
+		var cat runeRanges
+		negated := unicode.IsUpper(s[1])
+		switch s[1] {
+		case 's', 'S':
+			cat = tableRanges(unicode.White_Space)
+		case 'w', 'W':
+			cat = wordRanges
+		case 'p', 'P':
+			end := indexRune(s, '}')
+			if len(s) < 4 || s[2] != '{' || end < 0 {
+				return nil, nil, false
+			}
+			table := categoryTable(string(s[3:end]))
+			if table == nil {
+				return nil, nil, false
+			}
+			cat = tableRanges(table)
+			s = s[end-1:]
+		default:
+			return nil, nil, false
+		}
+		if negated {
+			result = result.union(undecided.subtract(cat))
+			undecided = runeRanges{}
+		} else {
+			result = result.union(undecided.intersect(cat))
+			undecided = undecided.subtract(cat)
+		}
+		s = s[2:]
+	}
+	if negate {
+		result = result.complement()
+	}
+
+	// 最后是减掉的字符集
+	if len(s) > 0 && s[0] == '-' {
+		sub, rest, ok := parseSet(s[1:], categories)
+		if !ok {
+			return nil, nil, false
+		}
+		result = result.subtract(sub)
+		s = rest
+	}
+	if len(s) == 0 || s[0] != ']' {
+		return nil, nil, false
+	}
+	return result, s[1:], true
+}
+
+// unescapeRune reads an escaped rune as written by syntax.CharDescription, and how
+// many runes it takes.
+func unescapeRune(s []rune) (rune, int, bool) {
+	switch s[1] {
+	case 'a':
+		return '\a', 2, true
+	case 'f':
+		return '\f', 2, true
+	case 'n':
+		return '\n', 2, true
+	case 'r':
+		return '\r', 2, true
+	case 't':
+		return '\t', 2, true
+	case 'v':
+		return '\v', 2, true
+	case 'x', 'u':
+		// \x 固定两位，\u 不补零，只能把后面的十六进制都读进来
+		max := 2
+		if s[1] == 'u' {
+			max = 6
+		}
+		ch, n := rune(0), 2
+		for n < len(s) && n-2 < max {
+			d, err := strconv.ParseInt(string(s[n]), 16, 32)
+			if err != nil {
+				break
+			}
+			ch = ch*16 + rune(d)
+			n++
+		}
+		for ch > unicode.MaxRune {
+			ch /= 16
+			n--
+		}
+		if n == 2 {
+			return 0, 0, false
+		}
+		return ch, n, true
+	}
+	return s[1], 2, true
+}
+
+// categoryTable finds a \p name the way regexp2 does, properties before
+// categories before scripts.
+func categoryTable(name string) *unicode.RangeTable {
+	if t, ok := unicode.Properties[name]; ok {
+		return t
+	}
+	if t, ok := unicode.Categories[name]; ok {
+		return t
+	}
+	return unicode.Scripts[name]
+}
+
+// wordRanges is \w, as syntax.IsWordChar.
+var wordRanges = func() runeRanges {
+	rs := runeRanges{{0x200c, 0x200d}}
+	for _, name := range []string{"L", "Mn", "Nd", "Pc"} {
+		rs = rs.union(tableRanges(unicode.Categories[name]))
+	}
+	return rs
+}()
+
+func indexRune(s []rune, r rune) int {
+	for i, c := range s {
+		if c == r {
+			return i
+		}
+	}
+	return -1
+}
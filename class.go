@@ -0,0 +1,132 @@
+package regexp2gen
+
+import (
+	"github.com/dlclark/regexp2/syntax"
+)
+
+// Class is a kind of char instruction that can have an alphabet of its own, see WithClassAlphabet.
+type Class int
+
+const (
+	// ClassAny is ., with or without (?s).
+	ClassAny Class = iota
+	// ClassWord is \w.
+	ClassWord
+	// ClassDigit is \d.
+	ClassDigit
+	// ClassSpace is \s.
+	ClassSpace
+	// ClassNegated is a negated char or set, such as [^a-z], \W or [^\d].
+	ClassNegated
+	// ClassBoundary is the non-word runes written next to a \b
+	// when the alphabet has none that fits, as the . of a\b.
+	ClassBoundary
+)
+
+// 编译后的字符集没有记录来源，只能按内容比较
+var classes = func() map[string]Class {
+	m := map[string]Class{}
+	add := func(class Class, sets ...func() *syntax.CharSet) {
+		for _, set := range sets {
+			m[set().String()] = class
+		}
+	}
+	add(ClassAny, syntax.AnyClass)
+	add(ClassWord, syntax.WordClass, syntax.ECMAWordClass)
+	add(ClassDigit, syntax.DigitClass, syntax.ECMADigitClass)
+	add(ClassSpace, syntax.SpaceClass, syntax.ECMASpaceClass, syntax.RE2SpaceClass)
+	add(ClassNegated, syntax.NotWordClass, syntax.NotECMAWordClass, syntax.NotDigitClass,
+		syntax.NotECMADigitClass, syntax.NotSpaceClass, syntax.NotECMASpaceClass, syntax.NotRE2SpaceClass)
+	return m
+}()
+
+// alphabet is the runes a class prefers, a rune without a weight counts as 1.
+type alphabet struct {
+	chars   []rune
+	weights map[rune]float64
+}
+
+func newAlphabet(chars []rune, weights []float64) alphabet {
+	a := alphabet{chars: chars}
+	if len(weights) > 0 {
+		a.weights = make(map[rune]float64, len(weights))
+		for i, w := range weights {
+			if i < len(chars) {
+				a.weights[chars[i]] = w
+			}
+		}
+	}
+	return a
+}
+
+// class returns the class of the char instruction at index,
+// false when it only uses the alphabet, as [a-z].
+func (p *program) class(index int) (Class, bool) {
+	switch p.op(index) {
+	case syntax.Notone, syntax.Notonerep, syntax.Notoneloop, syntax.Notonelazy:
+		// 没有 (?s) 的 . 是 [^\n]
+		if p.Codes[index+1] == '\n' {
+			return ClassAny, true
+		}
+		return ClassNegated, true
+	case syntax.Set, syntax.Setrep, syntax.Setloop, syntax.Setlazy:
+		set := p.Sets[p.Codes[index+1]]
+		if class, ok := classes[set.String()]; ok {
+			return class, true
+		}
+		if set.IsNegated() {
+			return ClassNegated, true
+		}
+	}
+	return 0, false
+}
+
+// alphabet returns the runes of class, or the alphabet when it has none of its own.
+func (s *State) alphabet(class Class) alphabet {
+	if a, ok := s.classes[class]; ok {
+		return a
+	}
+	if class == ClassBoundary {
+		return alphabet{chars: []rune{defaultBoundary}}
+	}
+	return alphabet{chars: s.chars}
+}
+
+// alphabet returns the runes the char instruction at index prefers.
+func (r *runner) alphabet(index int) alphabet {
+	a := alphabet{chars: r.s.chars}
+	if class, ok := r.c.class(index); ok {
+		a = r.s.alphabet(class)
+	}
+	if r.s.literals {
+		a = a.with(r.c.literalRunes())
+	}
+	return a
+}
+
+// with adds lits to the alphabet, together they are about as likely as the runes it had.
+func (a alphabet) with(lits []rune) alphabet {
+	if len(lits) == 0 {
+		return a
+	}
+	w := float64(len(a.chars)) / float64(len(lits))
+	if w < 1 {
+		w = 1
+	}
+	result := alphabet{
+		chars:   append([]rune{}, a.chars...),
+		weights: make(map[rune]float64, len(a.weights)+len(lits)),
+	}
+	for ch, v := range a.weights {
+		result.weights[ch] = v
+	}
+	for _, ch := range lits {
+		if !containsRune(result.chars, ch) {
+			result.chars = append(result.chars, ch)
+		}
+		if weight(result.weights, ch) < w {
+			result.weights[ch] = w
+		}
+	}
+	return result
+}
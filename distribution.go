@@ -0,0 +1,38 @@
+package regexp2gen
+
+// Distribution returns the weight of each repetition count from min to max
+// of a quantifier, unbounded quantifiers use max = min + limit.
+type Distribution func(min, max int) []float64
+
+// UniformDistribution draws every count with the same weight.
+func UniformDistribution(min, max int) []float64 {
+	weights := make([]float64, max-min+1)
+	for i := range weights {
+		weights[i] = 1
+	}
+	return weights
+}
+
+// GeometricDistribution favors short repetitions, each extra repetition is p times as likely.
+func GeometricDistribution(p float64) Distribution {
+	return func(min, max int) []float64 {
+		weights := make([]float64, max-min+1)
+		w := 1.0
+		for i := range weights {
+			weights[i] = w
+			w *= p
+		}
+		return weights
+	}
+}
+
+// BoundaryDistribution draws min and max half of the time, for testing length validators.
+func BoundaryDistribution(min, max int) []float64 {
+	weights := UniformDistribution(min, max)
+	if len(weights) > 2 {
+		inner := float64(len(weights) - 2)
+		weights[0] = inner / 2
+		weights[len(weights)-1] = inner / 2
+	}
+	return weights
+}
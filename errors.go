@@ -0,0 +1,172 @@
+package regexp2gen
+
+import (
+	"fmt"
+
+	"github.com/dlclark/regexp2/syntax"
+)
+
+/*
+The errors of a generation that did not work out. Index is the opcode index
+of the instruction at fault and Offset where it is in the pattern, -1 when
+that can not be told. Kind names the construct, as `\A`, "backreference" or
+"atomic group".
+
+	var unsupported *UnsupportedConstructError
+	if errors.As(err, &unsupported) {
+		// skip the pattern
+	}
+*/
+
+// UnsupportedConstructError is returned for a construct the generator does not handle.
+type UnsupportedConstructError struct {
+	Index  int
+	Offset int
+	Kind   string
+}
+
+func (e *UnsupportedConstructError) Error() string {
+	return fmt.Sprintf("unsupported %s at %s", e.Kind, position(e.Index, e.Offset))
+}
+
+// UnsatisfiableError is returned when the construct can never match, as a
+// backreference to a group that is not captured yet, \A or \G after text, \z
+// before text, or an atomic group that only matches when backtracked into.
+// Partial is the text generated up to there.
+type UnsatisfiableError struct {
+	Index   int
+	Offset  int
+	Kind    string
+	Partial string
+}
+
+func (e *UnsatisfiableError) Error() string {
+	return fmt.Sprintf("%s: %s can not match at %s", errGenerateFail, e.Kind, position(e.Index, e.Offset))
+}
+
+// Unwrap returns the generic generation failure the error explains.
+func (e *UnsatisfiableError) Unwrap() error {
+	return errGenerateFail
+}
+
+// ExhaustedError is returned when the attempts ran out without a string and
+// nothing proves the pattern can not match. The construct is where the last
+// attempt got the deepest before failing, or the constraint that ruled out the
+// text there, as the \b of x\bx. Partial is the text generated up to there.
+type ExhaustedError struct {
+	Index   int
+	Offset  int
+	Kind    string
+	Partial string
+}
+
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("%s: gave up on %s at %s", errGenerateFail, e.Kind, position(e.Index, e.Offset))
+}
+
+// Unwrap returns the generic generation failure the error explains.
+func (e *ExhaustedError) Unwrap() error {
+	return errGenerateFail
+}
+
+// VerificationError is returned when regexp2 does not match the generated string,
+// or Err, when it fails to run.
+type VerificationError struct {
+	Partial string
+	Err     error
+}
+
+func (e *VerificationError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("verify %q: %s", e.Partial, e.Err)
+	}
+	return fmt.Sprintf("%s: %q does not match", errGenerateFail, e.Partial)
+}
+
+func (e *VerificationError) Unwrap() error {
+	if e.Err != nil {
+		return e.Err
+	}
+	return errGenerateFail
+}
+
+func position(index, offset int) string {
+	if offset < 0 {
+		return fmt.Sprintf("opcode %d", index)
+	}
+	return fmt.Sprintf("opcode %d, offset %d", index, offset)
+}
+
+// unsatisfiable reports whether the instruction at index can never match,
+// so a failure there is not down to the random choices.
+func (p *program) unsatisfiable(index int) bool {
+	switch p.op(index) {
+	case syntax.Beginning, syntax.Start:
+		return p.afterText(index)
+	case syntax.End:
+		return p.beforeText(index)
+	case syntax.Ref:
+		support, _, _ := p.refSupport(index)
+		return support == Unsatisfiable
+	}
+	return false
+}
+
+// kind names the construct of the instruction at index.
+func (p *program) kind(index int) string {
+	op := p.op(index)
+	if name, ok := anchorNames[op]; ok {
+		// ^ $ 可以编译成 \A \Z \z，按模式里的写法
+		if offset := p.offset(index); offset >= 0 && (p.pattern[offset] == '^' || p.pattern[offset] == '$') {
+			return p.pattern[offset : offset+1]
+		}
+		return name
+	}
+	switch op {
+	case syntax.One, syntax.Onerep, syntax.Oneloop, syntax.Onelazy:
+		return "char"
+	case syntax.Notone, syntax.Notonerep, syntax.Notoneloop, syntax.Notonelazy:
+		return "negated char"
+	case syntax.Set, syntax.Setrep, syntax.Setloop, syntax.Setlazy:
+		return "char class"
+	case syntax.Multi:
+		return "string"
+	case syntax.Ref:
+		return "backreference"
+	case syntax.Boundary, syntax.ECMABoundary:
+		return `\b`
+	case syntax.Nonboundary, syntax.NonECMABoundary:
+		return `\B`
+	case syntax.Setjump:
+		if p.op(index+1) == syntax.Lazybranch && p.op(index+3) == syntax.Testref {
+			return "conditional"
+		}
+		if p.op(index+1) == syntax.Setmark && p.op(index+2) == syntax.Lazybranch {
+			if _, ok := p.condition(index + 2); ok {
+				return "conditional"
+			}
+		}
+		if p.atomic(index) {
+			return "atomic group"
+		}
+		if _, ok := p.prevent(index); ok {
+			return "negative lookaround"
+		}
+		return "lookaround"
+	case syntax.Capturemark:
+		if p.Codes[index+2] != -1 {
+			return "balancing group"
+		}
+		return "group"
+	case syntax.Testref:
+		return "conditional"
+	case syntax.Lazybranch:
+		if _, ok := p.condition(index); ok || p.op(index+2) == syntax.Testref {
+			return "conditional"
+		}
+		return "alternation"
+	case syntax.Branchmark, syntax.Lazybranchmark, syntax.Branchcount, syntax.Lazybranchcount:
+		return "loop"
+	}
+	return fmt.Sprintf("opcode %d", op)
+}
@@ -1,17 +1,27 @@
 package regexp2gen
 
 import (
-	"bytes"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"math"
+	"sync"
+	"time"
+	"unicode"
 
 	"github.com/dlclark/regexp2"
 	"github.com/dlclark/regexp2/syntax"
 )
 
-type Generator struct{}
+// Generator generates strings for patterns, see New. It can be shared between
+// goroutines, their generations take turns on its random source.
+type Generator struct {
+	// 保护 state 里的随机数
+	mu      sync.Mutex
+	state   *State
+	options regexp2.RegexOptions
+	// trial generations of Analyze
+	trials int
+}
 
 func opcodeSize(op syntax.InstOp) int {
 	op &= syntax.Mask
@@ -34,195 +44,211 @@ func opcodeSize(op syntax.InstOp) int {
 	}
 }
 
-func (g *Generator) printCode(c *syntax.Code) {
-	fmt.Println(c.Codes)
-	buf := &bytes.Buffer{}
+func (g *Generator) traceCode(t Tracer, c *syntax.Code) {
 	for i := 0; i < len(c.Codes); i += opcodeSize(syntax.InstOp(c.Codes[i])) {
-		fmt.Fprintln(buf, c.OpcodeDescription(i))
+		t.Trace(Event{Kind: EventCode, Index: i, Text: c.OpcodeDescription(i)})
 	}
-	fmt.Println(buf.String())
 }
 
-func (g *Generator) Generate(s *state, re string, op regexp2.RegexOptions) (string, error) {
-	if s.debug {
-		fmt.Println(re)
-	}
+// Result is a generated string and how many attempts it took.
+type Result struct {
+	String   string
+	Attempts int
+}
 
-	reg, err := regexp2.Compile(re, op)
+// Generate returns a random string matched by the pattern re.
+func (g *Generator) Generate(re string) (string, error) {
+	result, err := g.GenerateResult(re)
 	if err != nil {
 		return "", err
 	}
+	return result.String, nil
+}
 
-	tree, err := syntax.Parse(re, syntax.RegexOptions(op))
-	if err != nil {
-		return "", err
+// GenerateResult is like Generate but also reports the attempts used.
+func (g *Generator) GenerateResult(re string) (*Result, error) {
+	s := g.state
+	if s == nil {
+		// Generator{} 没有经过 New，每次用新的状态
+		s = NewState(false, defaultLimit, nil, time.Now().UnixNano())
+	} else {
+		g.mu.Lock()
+		defer g.mu.Unlock()
 	}
-	c, err := syntax.Write(tree)
+	return g.generateResult(s, re, g.options)
+}
+
+// GenerateWithState generates with an explicit state and options,
+// it is kept for callers of the NewState API. The state must not be used
+// by other goroutines at the same time.
+func (g *Generator) GenerateWithState(s *State, re string, op regexp2.RegexOptions) (string, error) {
+	result, err := g.generateResult(s, re, op)
 	if err != nil {
 		return "", err
 	}
+	return result.String, nil
+}
+
+func (g *Generator) generateResult(s *State, re string, op regexp2.RegexOptions) (*Result, error) {
+	if s.tracer != nil {
+		s.tracer.Trace(Event{Kind: EventPattern, Index: -1, Text: re})
+	}
 
-	result, err := g.generate(s, c)
+	reg, err := regexp2.Compile(re, op)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	ok, err := reg.MatchString(result)
+	p, err := compile(re, op)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if !ok {
-		return "", errors.New("generate string fail")
+	if s.tracer != nil {
+		g.traceCode(s.tracer, p.Code)
 	}
 
-	return result, nil
+	err = errGenerateFail
+	for attempt := 1; attempt <= s.attempts; attempt++ {
+		if s.tracer != nil {
+			s.tracer.Trace(Event{Kind: EventAttempt, Index: -1, Attempt: attempt})
+		}
+		var result string
+		result, err = g.generate(s, p, reg.MatchString)
+		if errors.Is(err, errGenerateFail) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &Result{String: result, Attempts: attempt}, nil
+	}
+
+	if atomic := looseAtomic(s, p); atomic != nil {
+		return nil, atomic
+	}
+	return nil, err
+}
+
+// looseAtomic returns the error for the first atomic group of p that lets the code
+// match only when it is matched as an ordinary group, nil when there is none.
+func looseAtomic(s *State, p *program) *UnsatisfiableError {
+	// 把原子组当作普通的组能生成，说明只有回溯进原子组才能匹配，例如 (?>a+)a
+	for _, index := range p.atomics {
+		r := newRunner(s, p, newBuffer())
+		r.loose = index
+		if r.run() == nil {
+			return &UnsatisfiableError{Index: index, Offset: p.offset(index), Kind: p.kind(index), Partial: r.m.buf.String()}
+		}
+	}
+	return nil
 }
 
 /*
 TODO： 这里只实现了简单的罗列，没有考虑一些非匹配和匹配之间相互影响的问题
+生成失败时回到最近的选择点重新选择，选择点用完后由调用方重新开始
 */
-func (g *Generator) generate(s *state, c *syntax.Code) (string, error) {
-	if s.debug {
-		g.printCode(c)
+func (g *Generator) generate(s *State, c *program, accept func(string) (bool, error)) (string, error) {
+	r := newRunner(s, c, newBuffer())
+	r.accept = accept
+	r.tracer = s.tracer
+
+	err := r.run()
+	if err == errGenerateFail {
+		// 最后失败的地方多半就是不可能满足的地方，例如 a\G 的 \G
+		if r.rejected {
+			return "", &VerificationError{Partial: r.partial}
+		}
+		if r.failed >= 0 && c.unsatisfiable(r.failed) {
+			return "", &UnsatisfiableError{Index: r.failed, Offset: c.offset(r.failed), Kind: c.kind(r.failed), Partial: r.partial}
+		}
+		if r.failed >= 0 {
+			return "", &ExhaustedError{Index: r.failed, Offset: c.offset(r.failed), Kind: c.kind(r.failed), Partial: r.partial}
+		}
 	}
+	if err != nil {
+		return "", err
+	}
+	r.trace(Event{Kind: EventResult, Index: -1, Text: r.m.buf.String()})
+	return r.m.buf.String(), nil
+}
 
-	buf := NewBuffer()
-	index := 0
+// run walks the code until stop or the end of the code, backtracking on failures.
+func (r *runner) run() error {
+	s, c := r.s, r.c
 
-	// 记录 set count 的值
-	setCountNum := []int{}
+	for {
+		if r.m.index >= len(c.Codes) || r.m.index == r.stop {
+			r.step()
+			ok, err := r.finish()
+			if err != nil {
+				return err
+			}
+			if ok {
+				return nil
+			}
+			if !r.backtrack() {
+				return errGenerateFail
+			}
+			continue
+		}
 
-	for index < len(c.Codes) {
+		m := r.m
+		index := m.index
+		buf := m.buf
+		fail := false
+		r.step()
+		r.trace(Event{Kind: EventOp, Index: index, Text: c.OpcodeDescription(index)})
+		pos := buf.Pos()
 		op := syntax.InstOp(c.Codes[index])
 		size := opcodeSize(op)
+		// 后顾和 RightToLeft 的字符从右向左写
+		rtl := op&syntax.Rtl != 0
 		op &= syntax.Mask
 
 		switch op {
-		case syntax.Onelazy, syntax.Notonelazy, syntax.Setlazy:
-			//{2,4}? -> rep(Rep = 2), lazy(Rep = 2)
-			//do nothing
-		case syntax.One, syntax.Onerep, syntax.Oneloop:
-			r := rune(c.Codes[index+1])
-			var length int
-			if size == 2 {
-				// one
-				length = 1
-			} else {
-				length = c.Codes[index+2]
-				if length == math.MaxInt32 {
-					//{2,4} -> rep(Rep = 2), loop(Rep = 2)
-					//add all loop, with 4 char
-					//{2,}, this will just write 2 char
-					//WARN: a{2,}[a-z] if we get `aaa` will fail to gen.
-					length = 0
-				}
-			}
-			result := s.randomRunes([]rune{r}, length)
-			for _, j := range result {
-				buf.WriteRune(j)
+		case syntax.One, syntax.Onerep, syntax.Oneloop, syntax.Onelazy,
+			syntax.Notone, syntax.Notonerep, syntax.Notoneloop, syntax.Notonelazy,
+			syntax.Set, syntax.Setrep, syntax.Setloop, syntax.Setlazy:
+			length := r.repeatCount(op, c, index, rtl)
+			// 单个字符再来一次也是同样的结果
+			single := op == syntax.One || op == syntax.Onerep || op == syntax.Oneloop || op == syntax.Onelazy
+			if !buf.frozen && r.remaining(rtl) < length && (!single || c.ci(index)) {
+				r.reroll()
 			}
-		case syntax.Notone, syntax.Notonerep, syntax.Notoneloop:
-			var length int
-			if size == 2 {
-				// notone
-				length = 1
-			} else {
-				length = c.Codes[index+2]
-				if length == math.MaxInt32 {
-					//{2,4} -> rep(Rep = 2), loop(Rep = 2)
-					//add all loop, with 4 char
-					//{2,}, this will just write 2 char
-					//WARN: a{2,}[a-z] if we get `aaa` will fail to gen.
-					length = 0
-				}
-			}
-			exclude := rune(c.Codes[index+1])
-			// get possible chars
-			possibleChars := []rune{}
-			if exclude == '.' {
-				possibleChars = s.chars
-			} else {
-				for j := 0; j < len(s.chars); j++ {
-					c := s.chars[j]
-					if c != exclude {
-						possibleChars = append(possibleChars, c)
-					}
-				}
-			}
-			result := s.randomRunes(possibleChars, length)
-			for _, j := range result {
-				buf.WriteRune(j)
-			}
-		case syntax.Set, syntax.Setrep, syntax.Setloop:
-			charSet := c.Sets[c.Codes[index+1]]
-			// 优先使用输入的字符集
-			possibleChars := []rune{}
-			for j := 0; j < len(s.chars); j++ {
-				c := s.chars[j]
-				if charSet.CharIn(c) {
-					possibleChars = append(possibleChars, c)
-				}
-			}
-			// 尝试寻找一个能满足的匹配项
-			// TODO：因为 charSet 没有提供相应的属性或者方法出来，所以这里愚蠢的遍历一遍尝试找一个
-			if len(possibleChars) == 0 {
-				r, err := resolveCharSet(charSet)
-				if err != nil {
-					return "", err
-				}
-				possibleChars = append(possibleChars, r)
+			ok, err := r.writeChars(index, length, rtl)
+			if err != nil {
+				return err
 			}
-
-			var length int
-			if size == 2 {
-				// set
-				length = 1
-			} else {
-				length = c.Codes[index+2]
-				if length == math.MaxInt32 {
-					//{2,4} -> rep(Rep = 2), loop(Rep = 2)
-					//add all loop, with 4 char
-					//{2,}, this will just write 2 char
-					//WARN: a{2,}[a-z] if we get `aaa` will fail to gen.
-					length = 0
+			fail = !ok
+			switch op {
+			case syntax.Oneloop, syntax.Notoneloop, syntax.Setloop:
+				if ok && !buf.frozen && r.atomicLoop(index) && length < c.Codes[index+2] {
+					fail = !r.greedy(index, rtl)
 				}
 			}
-			result := s.randomRunes(possibleChars, length)
-			for _, j := range result {
-				buf.WriteRune(j)
-			}
+			r.traceWrite(index, pos, buf.Pos())
 		case syntax.Multi:
-			for _, r := range c.Strings[c.Codes[index+1]] {
-				buf.WriteRune(r)
-			}
+			fail = !r.writeAll(c.Strings[c.Codes[index+1]], rtl, c.ci(index))
+			r.traceWrite(index, pos, buf.Pos())
 		case syntax.Ref:
 			refIndex := c.Codes[index+1]
-			groupBuffer, ok := buf.Getmark(refIndex)
+			group, ok := buf.Group(refIndex)
 			if !ok {
-				return "", fmt.Errorf("ref get index err: %d", refIndex)
+				// 分组还没有捕获：ECMAScript 匹配空串，否则这条路走不通，例如 \1(abc)
+				fail = c.options&regexp2.ECMAScript == 0
+				break
 			}
-			_, err := buf.WriteAll(groupBuffer.Bytes())
-			if err != nil {
-				return "", err
+			fail = !r.writeAll(group, rtl, c.ci(index))
+			r.traceWrite(index, pos, buf.Pos())
+
+		case syntax.Boundary, syntax.Nonboundary, syntax.ECMABoundary, syntax.NonECMABoundary:
+			// 零宽断言：两边的字符都确定时直接判断，否则在写入字符时约束
+			b := boundary{index: index, pos: buf.Pos(), op: op}
+			if ok, known := b.check(buf); known {
+				fail = !ok
+				break
 			}
-
-		/*
-			input: end sends endure lender
-			\bend\b -> (end)
-			\Bend\B -> s(end)s, l(end)er
-		*/
-		case syntax.Boundary:
-			// TODO: 无脑写
-			buf.WriteRune(s.boundary)
-		case syntax.Nonboundary:
-			// TODO: 无脑写
-			buf.WriteRune(s.randomRunes(s.chars, 1)[0])
-		case syntax.ECMABoundary:
-			// TODO: 无脑写
-			buf.WriteRune(s.boundary)
-		case syntax.NonECMABoundary:
-			// TODO: 无脑写
-			buf.WriteRune(s.randomRunes(s.chars, 1)[0])
+			m.boundaries = append(m.boundaries, b)
 
 		/*
 			^ Matches the beginning of a line.
@@ -261,85 +287,148 @@ func (g *Generator) generate(s *state, c *syntax.Code) (string, error) {
 			\AGoogle\nApple\z ->
 			\AGoogle\nApple\Z ->
 		*/
-		case syntax.Bol:
-		case syntax.Eol:
-		case syntax.Beginning:
-		case syntax.Start:
-		case syntax.EndZ:
-		case syntax.End:
+		case syntax.Bol, syntax.Eol, syntax.Beginning, syntax.Start, syntax.EndZ, syntax.End:
+			fail = !r.anchor(index, op)
 		case syntax.Nothing:
 
 		case syntax.Setmark:
 			buf.Setmark()
+			r.traceMark(index, true)
 		case syntax.Capturemark:
-			refIndex := c.Codes[index+1]
-			// TODO: 这里还有一个参数, 不知道是用来干啥的， unidex？？ 非捕获么？
-			err := buf.Backmark(true, refIndex)
+			// (?<name-other>...) -> Capturemark(name, other)，other 的最后一次捕获出栈
+			refIndex, uncapture := c.Codes[index+1], c.Codes[index+2]
+			r.traceMark(index, false)
+			ok, err := buf.Capture(refIndex, uncapture)
 			if err != nil {
-				return "", err
+				return err
 			}
+			// 限制平衡组的嵌套深度
+			fail = !ok || s.depth > 0 && refIndex != -1 && buf.Captures(refIndex) > s.depth
 		case syntax.Getmark:
-			// TODO: get mark 没有实现
-			// err := buf.Backmark(false, -1)
-			// if err != nil {
-			// 	return "", err
-			// }
-		case syntax.Branchmark:
-			err := buf.Backmark(false, -1)
+			/*
+				(?=...) -> Setjump Setmark ... Getmark Forejump
+				(?<=...) 相同，只是中间的内容从右向左
+				前瞻的内容已经写入，回到开始的位置，后面的内容需要和已经写入的字符一致；
+				后顾检查前面已经写入的字符，前面没有内容时在开头补上
+			*/
+			r.traceMark(index, false)
+			err := buf.Getmark()
 			if err != nil {
-				return "", err
+				return err
 			}
-		case syntax.Nullmark:
-			buf.Setmark()
-		case syntax.Lazybranchmark:
+		case syntax.Branchmark, syntax.Lazybranchmark:
+			// (...)* (...)+ 循环：第一次到达时在 limit 以内随机决定还要循环几次
+			l := len(m.loops)
+			if l == 0 || m.loops[l-1].index != index {
+				// (...)+ 进入循环前已经执行过一次
+				min := 1
+				if c.op(c.prev[c.Codes[index+1]]) == syntax.Goto {
+					min = 0
+				}
+				addr := c.Codes[index+1]
+				n := r.optional(math.MaxInt32, c.rightToLeft(addr, index))
+				if u, ok := c.balancing(addr, index); ok && buf.Captures(u) <= n {
+					// 平衡组的循环先尝试把还没有出栈的捕获都出栈
+					weights := make([]float64, n+1)
+					weights[buf.Captures(u)] = 1
+					n = r.choose(n+1, weights)
+				} else {
+					n = r.repeat(min, n)
+				}
+				m.loops = append(m.loops, loopFrame{index: index, remain: n})
+				l++
+			}
+			r.traceMark(index, false)
 			err := buf.Backmark(false, -1)
 			if err != nil {
-				return "", err
+				return err
+			}
+			if m.loops[l-1].remain > 0 {
+				m.loops[l-1].remain--
+				buf.Setmark()
+				r.traceMark(index, true)
+				size = c.Codes[index+1] - index
+			} else {
+				m.loops = m.loops[:l-1]
 			}
+		case syntax.Nullmark:
+			buf.Setmark()
+			r.traceMark(index, true)
 
 		case syntax.Setjump:
 			/*
-				TODO: 这个地方还没实现否定逻辑， 目前只是简单的跳过 jump 了
-				一旦出现这个证明出现了 ?!， 需要生成一个不符合其中正则的内容
-				需要把上面能产生实际内容的字符串生成的 case 都写一个否定逻辑然后在这里使用一下
+				(?!...) -> Setjump Lazybranch(A) ... Backjump A: Forejump
+				(?<!...) 相同，只是中间的内容从右向左
+				否定的前瞻记录下来，生成结束时证明后面的内容确实不匹配，
+				只有一个字符的情况直接在选字符的时候排除掉
 			*/
-			inner := index + 1
-			back := false
-			for inner < len(c.Codes) {
-				innerOp := syntax.InstOp(c.Codes[inner])
-				innerSize := opcodeSize(innerOp)
-				if innerOp == syntax.Backjump {
-					back = true
-				} else if innerOp == syntax.Forejump {
+			if end, ok := c.prevent(index); ok {
+				if !r.prevent(index, index+3, c.prev[end]) {
+					// 已经确定会匹配，例如 (?!)
+					fail = true
 					break
 				}
-				inner += innerSize
-			}
-			if back {
-				size = inner - index
+				size = end - index
 			}
 		case syntax.Forejump:
 		case syntax.Backjump:
 
 		case syntax.Lazybranch:
+			addr := c.Codes[index+1]
+			if c.op(index+2) == syntax.Testref {
+				// (?(1)yes|no) -> Setjump Lazybranch(A) Testref(1) Forejump yes Goto(E) A: Forejump no E
+				// 分组已经捕获时走 yes，否则走 no
+				if _, ok := buf.Group(c.Codes[index+3]); !ok {
+					size = addr - index
+				}
+			} else if stop, ok := c.condition(index); ok {
+				/*
+					(?(cond)yes|no) -> Setjump Setmark Lazybranch(A) cond Getmark Forejump yes Goto(E) A: Getmark Forejump no E
+					随机决定走哪个分支：yes 时 cond 和前瞻一样写入，no 时 cond 在这里不能匹配
+				*/
+				if r.choose(2, nil) == 1 {
+					fail = !r.prevent(index, index+2, stop)
+					size = addr - index
+				}
+			} else if starts := c.alternatives(index); starts != nil {
+				// 选择结构：随机选择一个分支，直接跳到分支开始的位置
+				var weights []float64
+				if n, ok := c.alternations[index]; ok {
+					weights = s.branchWeights[n]
+				}
+				size = starts[r.choose(len(starts), weights)] - index
+			} else if index == 0 && !buf.frozen {
+				// 匹配可以从字符串中间开始，需要的时候在匹配之前留一个字符，例如 (?!\A)x
+				if r.choose(2, []float64{1, 0}) == 1 {
+					fail = !r.outside(false)
+				}
+			}
 		case syntax.Nullcount:
 			num := c.Codes[index+1]
-			setCountNum = append(setCountNum, num)
+			m.setCountNum = append(m.setCountNum, setCount{num: num, min: 0, target: -1})
 		case syntax.Setcount:
+			// {m,n} -> Setcount(1-m)
 			num := c.Codes[index+1]
-			setCountNum = append(setCountNum, num)
+			m.setCountNum = append(m.setCountNum, setCount{num: num, min: 1 - num, target: -1})
 		case syntax.Branchcount, syntax.Lazybranchcount:
-			if len(setCountNum) == 0 {
-				return "", fmt.Errorf("unknown branch count")
+			l := len(m.setCountNum)
+			if l == 0 {
+				// 没有 Setcount 的计数循环
+				return &UnsupportedConstructError{Index: index, Offset: c.offset(index), Kind: c.kind(index)}
 			}
-			num := setCountNum[len(setCountNum)-1]
+			count := m.setCountNum[l-1]
 			addr := c.Codes[index+1]
 			limit := c.Codes[index+2]
-			if num >= 0 && (limit == math.MaxInt32 || num == limit) {
+			if count.num >= 0 && count.target < 0 {
+				// 必须的次数已经完成，决定可选的次数
+				count.target = r.repeat(count.min, r.optional(limit, c.rightToLeft(addr, index)))
+			}
+			if count.num >= 0 && count.num >= count.target {
 				// 完成
-				setCountNum = setCountNum[:len(setCountNum)-1]
+				m.setCountNum = m.setCountNum[:l-1]
 			} else {
-				setCountNum[len(setCountNum)-1] = num + 1
+				count.num++
+				m.setCountNum[l-1] = count
 				// 跳转到 addr
 				size = addr - index
 			}
@@ -350,20 +439,528 @@ func (g *Generator) generate(s *state, c *syntax.Code) (string, error) {
 			newIndex := c.Codes[index+1]
 			size = newIndex - index
 		case syntax.Prune:
+			// regexp2 不会生成 Prune
+			return &UnsupportedConstructError{Index: index, Offset: c.offset(index), Kind: "Prune"}
 		case syntax.Stop:
+			// 代码的结尾，子匹配的时候是 r.stop
+			m.index = len(c.Codes)
+			continue
 		default:
-			return "", fmt.Errorf("unknown code %d", op)
+			return &UnsupportedConstructError{Index: index, Offset: c.offset(index), Kind: c.kind(index)}
+		}
+		if fail {
+			blame := index
+			if r.cause >= 0 {
+				// 字符被前面的约束排除了，例如 x\bx 的 \b
+				blame = r.cause
+			}
+			r.fail(index, blame)
+			if !r.backtrack() {
+				return errGenerateFail
+			}
+			continue
+		}
+		m.index = index + size
+	}
+}
+
+// repeatCount returns how many times the char instruction at index writes its char.
+// {2,4} -> rep(Rep = 2), loop(Rep = 2)
+func (r *runner) repeatCount(op syntax.InstOp, c *program, index int, rtl bool) int {
+	switch op {
+	case syntax.One, syntax.Notone, syntax.Set:
+		return 1
+	case syntax.Onerep, syntax.Notonerep, syntax.Setrep:
+		return c.Codes[index+2]
+	}
+
+	// 前面的 rep 是必须的次数
+	min := 0
+	// Oneloop Onelazy -> Onerep, Notoneloop Notonelazy -> Notonerep, Setloop Setlazy -> Setrep
+	rep := op % 3
+	if prev, ok := c.prev[index]; ok && c.op(prev) == rep && c.Codes[prev+1] == c.Codes[index+1] {
+		min = c.Codes[prev+2]
+	}
+	if !r.m.buf.frozen && r.blocked(index, rtl) {
+		// 下一个字符已经写不了，例如 (?=(a*)(a*)) 的第二个 a*
+		return 0
+	}
+	switch op {
+	case syntax.Onelazy, syntax.Notonelazy, syntax.Setlazy:
+		if !r.m.buf.frozen && r.atomicLoop(index) {
+			// 懒惰的循环只取最少的次数，例如 (?=(a+?))
+			return 0
 		}
-		index += size
 	}
-	if s.debug {
-		fmt.Println(hex.Dump(buf.Bytes()))
+	return r.repeat(min, r.optional(c.Codes[index+2], rtl))
+}
+
+// blocked reports whether the char instruction at index can not write
+// the next rune in the direction rtl.
+func (r *runner) blocked(index int, rtl bool) bool {
+	pos := r.m.buf.Pos()
+	if rtl {
+		pos--
 	}
+	if ch, ok := r.m.buf.At(pos); ok {
+		return !r.c.charIn(index, ch) || r.excluded(pos, ch)
+	}
+	candidates, err := r.candidates(index)
+	if err != nil {
+		return false
+	}
+	for _, ch := range candidates {
+		if !r.excluded(pos, ch) {
+			return false
+		}
+	}
+	return true
+}
 
-	return buf.String(), nil
+// atomicLoop reports whether the char loop at index has to match
+// as regexp2 does without backtracking, see program.atomicLoop.
+func (r *runner) atomicLoop(index int) bool {
+	setjump, ok := r.c.atomicLoop(index)
+	return ok && setjump != r.loose
+}
+
+// greedy makes the greedy loop at index, which ends at the cursor, stop there:
+// the next rune in its direction can not be one it matches.
+func (r *runner) greedy(index int, rtl bool) bool {
+	g := greedy{pos: r.m.buf.Pos(), index: index}
+	if rtl {
+		g.pos--
+	}
+	if ch, ok := r.m.buf.At(g.pos); ok && r.c.charIn(index, ch) {
+		return false
+	}
+	r.m.greedies = append(r.m.greedies, g)
+	return true
+}
+
+// optional returns how many of n optional repetitions can be made
+// by a quantifier matching in the direction rtl.
+func (r *runner) optional(n int, rtl bool) int {
+	rem := r.remaining(rtl)
+	if r.m.buf.frozen {
+		// 每次循环至少匹配一个字符
+		if n > rem {
+			return rem
+		}
+		return n
+	}
+	if n == math.MaxInt32 {
+		// {2,} * + 在 limit 以内随机，前瞻已经写入的部分不算
+		n = r.s.limit
+		if rem > n {
+			n = rem
+		}
+	}
+	return n
+}
+
+// remaining returns how many decided runes are ahead of the cursor in the direction rtl.
+func (r *runner) remaining(rtl bool) int {
+	if rtl {
+		return r.m.buf.RemainingBack()
+	}
+	return r.m.buf.Remaining()
+}
+
+// writeAll writes p at the cursor, or so that it ends at the cursor when rtl.
+// When ci the case of every rune is chosen at random.
+func (r *runner) writeAll(p []rune, rtl, ci bool) bool {
+	buf := r.m.buf
+	for i := range p {
+		ch, at, write := p[i], buf.Pos(), buf.WriteRune
+		if rtl {
+			ch, at, write = p[len(p)-1-i], at-1, buf.WriteRuneBack
+		}
+		if ci {
+			// 已经确定的字符只要忽略大小写相同
+			if d, ok := buf.At(at); ok && unicode.ToLower(d) == unicode.ToLower(ch) {
+				ch = d
+			} else if v := r.fold(ch); !r.excluded(at, v) {
+				ch = v
+			}
+		}
+		if r.excluded(at, ch) {
+			return false
+		}
+		if d, ok := buf.At(at); ok && d != ch && !r.replace(at, ch) {
+			return false
+		}
+		if !write(ch) {
+			r.blameAnchor(at, rtl)
+			return false
+		}
+		buf.Own(at, -1)
+	}
+	return true
+}
+
+// replace changes the rune at pos to ch when the char instruction that picked it
+// matches ch too, as the \w+ of \w+(?<=error) when the lookbehind comes to it.
+func (r *runner) replace(pos int, ch rune) bool {
+	owner := r.m.buf.Owner(pos)
+	if owner < 0 || !r.c.charIn(owner, ch) || r.excluded(pos, ch) {
+		return false
+	}
+	r.m.buf.Replace(pos, ch)
+	return true
+}
+
+// blameAnchor keeps the anchor that closed the text as the cause of the failure
+// to write at pos outside it, as the $ of x$y.
+func (r *runner) blameAnchor(pos int, rtl bool) {
+	if _, ok := r.m.buf.At(pos); ok {
+		return
+	}
+	if index := r.m.buf.Anchor(rtl); index >= 0 {
+		r.cause = index
+	}
+}
+
+// fold returns ch in a random case that has the same lower case,
+// as regexp2 compares runes by unicode.ToLower when ignoring case.
+// regexp2 has no culture specific rules, so this is what CultureInvariant means too.
+func (r *runner) fold(ch rune) rune {
+	lower := unicode.ToLower(ch)
+	variants := []rune{lower}
+	for _, v := range []rune{unicode.ToUpper(lower), unicode.ToTitle(lower)} {
+		if unicode.ToLower(v) == lower && v != variants[len(variants)-1] && v != lower {
+			variants = append(variants, v)
+		}
+	}
+	return r.s.randomRunes(variants, 1)[0]
+}
+
+// finish checks what can only be checked on the whole text.
+func (r *runner) finish() (bool, error) {
+	defer func() {
+		r.padded = false
+	}()
+	if r.accept != nil && r.choose(2, []float64{1, 0}) == 1 {
+		// 需要的时候在匹配之后留一个字符，例如 x(?!\z)
+		r.unpadded, r.padded = r.m.buf.String(), true
+		if !r.outside(true) {
+			return false, nil
+		}
+	}
+	// 结束时的检查比所有的指令都深
+	end := len(r.c.Codes)
+	if !r.settle() {
+		return false, nil
+	}
+	for _, p := range r.m.prevents {
+		if r.matches(p.start, p.stop, p.pos) {
+			r.fail(end, p.index)
+			return false, nil
+		}
+	}
+	for _, g := range r.m.greedies {
+		if ch, ok := r.m.buf.At(g.pos); ok && r.c.charIn(g.index, ch) {
+			r.fail(end, g.index)
+			return false, nil
+		}
+	}
+	if r.accept == nil {
+		return true, nil
+	}
+	text := r.m.buf.String()
+	ok, err := r.accept(text)
+	if err != nil {
+		return false, &VerificationError{Partial: r.text(), Err: err}
+	}
+	r.trace(Event{Kind: EventVerify, Index: -1, Text: text, Matched: ok})
+	if !ok && end+1 > r.depth {
+		r.failed, r.depth, r.partial, r.rejected = -1, end+1, r.text(), true
+	}
+	return ok, nil
+}
+
+// fail records that the op at depth failed, blamed on the op at index,
+// when no failure got that deep before.
+func (r *runner) fail(depth, index int) {
+	if depth > r.depth {
+		r.failed, r.depth, r.partial, r.rejected = index, depth, r.text(), false
+	}
+}
+
+// text returns the generated text without the rune written outside the match.
+func (r *runner) text() string {
+	if r.padded {
+		return r.unpadded
+	}
+	return r.m.buf.String()
+}
+
+// matches reports whether the code from start to stop matches the text at pos.
+func (r *runner) matches(start, stop, pos int) bool {
+	sub := newRunner(r.s, r.c, r.m.buf.Freeze(pos))
+	sub.m.index = start
+	sub.stop = stop
+	sub.budget = matchBacktracks
+	return sub.run() == nil
+}
+
+// writeChars writes length runes matched by the char instruction at index,
+// before the cursor when rtl.
+func (r *runner) writeChars(index, length int, rtl bool) (bool, error) {
+	buf := r.m.buf
+	next, write := buf.Next, buf.WriteRune
+	at := buf.Pos
+	if rtl {
+		next, write = buf.Prev, buf.WriteRuneBack
+		at = func() int { return buf.Pos() - 1 }
+	}
+	var candidates []rune
+	weights := r.alphabet(index).weights
+	for i := 0; i < length; i++ {
+		if ch, ok := next(); ok {
+			// 前瞻或者后顾已经决定了这个字符
+			pos := at()
+			if !r.c.charIn(index, ch) || r.excluded(pos, ch) {
+				// 随机选的字符可以换成两条指令都匹配的，例如 \w+(?<=\d) 的 \w
+				if candidates == nil {
+					var err error
+					candidates, err = r.candidates(index)
+					if err != nil {
+						return false, err
+					}
+				}
+				owner := buf.Owner(pos)
+				allowed := []rune{}
+				for _, v := range candidates {
+					if owner >= 0 && r.c.charIn(owner, v) && !r.excluded(pos, v) {
+						allowed = append(allowed, v)
+					}
+				}
+				if len(allowed) == 0 {
+					return false, nil
+				}
+				ch = r.s.randomRunes(allowed, 1)[0]
+				buf.Replace(pos, ch)
+			}
+			write(ch)
+			buf.Own(pos, -1)
+			continue
+		}
+		if buf.frozen {
+			return false, nil
+		}
+		if candidates == nil {
+			var err error
+			candidates, err = r.candidates(index)
+			if err != nil {
+				return false, err
+			}
+		}
+		allowed := make([]rune, 0, len(candidates))
+		forced := false
+		for _, ch := range candidates {
+			if !r.excluded(at(), ch) {
+				allowed = append(allowed, ch)
+			}
+		}
+		if r.c.newline(index, rtl) && r.c.charIn(index, '\n') {
+			// 后面是行首，例如 (?m)[\s\S]^，放得下的时候写完整的换行
+			if n := len(r.s.lineEnding); n > 1 && length-i == n && r.writeLineEnding(index, rtl) {
+				break
+			}
+			if i == length-1 && !r.excluded(at(), '\n') {
+				allowed = []rune{'\n'}
+				forced = true
+			}
+		}
+		if len(allowed) == 0 {
+			// 字母表里没有合适的字符时尝试分隔符，例如 a\b. 的 .
+			for _, ch := range r.s.alphabet(ClassBoundary).chars {
+				if r.c.charIn(index, ch) && !r.excluded(at(), ch) {
+					allowed = append(allowed, ch)
+				}
+			}
+			if len(allowed) == 0 {
+				return false, nil
+			}
+		}
+		ch := r.s.randomRune(allowed, weights)
+		if ch == '\n' && len(r.s.lineEnding) > 1 && !forced && !r.lineEnded(rtl) {
+			// 换行按设置的样式写入，例如 \r\n，放不下的时候换一个字符
+			if length-i >= len(r.s.lineEnding) && r.writeLineEnding(index, rtl) {
+				i += len(r.s.lineEnding) - 1
+				continue
+			}
+			if others := removeRune(allowed, '\n'); len(others) > 0 {
+				ch = r.s.randomRunes(others, 1)[0]
+			}
+		}
+		if r.c.ci(index) {
+			// 忽略大小写时随机大小写
+			if v := r.fold(ch); r.c.charIn(index, v) && !r.excluded(at(), v) {
+				ch = v
+			}
+		}
+		pos := at()
+		if !write(ch) {
+			// 字符串的边界已经确定，换别的字符也写不下
+			r.edge = true
+			r.blameAnchor(pos, rtl)
+			return false, nil
+		}
+		if !forced {
+			buf.Own(pos, index)
+		}
+	}
+	return true, nil
+}
+
+// writeLineEnding writes the line ending for the char instruction at index
+// when every rune of it fits there.
+func (r *runner) writeLineEnding(index int, rtl bool) bool {
+	buf := r.m.buf
+	ending := r.s.lineEnding
+	for k, ch := range ending {
+		pos := buf.Pos() + k
+		if rtl {
+			pos = buf.Pos() - len(ending) + k
+		}
+		if d, ok := buf.At(pos); ok && d != ch || !r.c.charIn(index, ch) || r.excluded(pos, ch) {
+			return false
+		}
+	}
+	if rtl {
+		return buf.WriteAllBack(ending)
+	}
+	return buf.WriteAll(ending)
+}
+
+// lineEnded reports whether the runes before the cursor are the line ending
+// without its last '\n', so a single '\n' completes it.
+func (r *runner) lineEnded(rtl bool) bool {
+	if rtl {
+		return false
+	}
+	buf := r.m.buf
+	ending := r.s.lineEnding[:len(r.s.lineEnding)-1]
+	for k, ch := range ending {
+		if d, ok := buf.At(buf.Pos() - len(ending) + k); !ok || d != ch {
+			return false
+		}
+	}
+	return true
+}
+
+// candidates returns the runes the char instruction at index picks from.
+func (r *runner) candidates(index int) ([]rune, error) {
+	s, c := r.s, r.c
+	switch c.op(index) {
+	case syntax.One, syntax.Onerep, syntax.Oneloop, syntax.Onelazy:
+		return []rune{rune(c.Codes[index+1])}, nil
+	}
+
+	// 优先使用输入的字符集
+	chars := r.alphabet(index).chars
+	possibleChars := []rune{}
+	for j := 0; j < len(chars); j++ {
+		ch := chars[j]
+		if c.charIn(index, ch) {
+			possibleChars = append(possibleChars, ch)
+		}
+	}
+	// (?s). 可以换行
+	if c.anyChar(index) {
+		for _, ch := range s.lineEnding {
+			if !containsRune(possibleChars, ch) {
+				possibleChars = append(possibleChars, ch)
+			}
+		}
+	}
+	if len(possibleChars) == 0 {
+		// 字母表里没有的时候从整个字符集里随机取
+		members := c.charSet(index)
+		if s.printable {
+			// 只取能显示的字符，字符集里没有的时候不限制，例如 \p{Cc}
+			if p := members.intersect(graphic); len(p) > 0 {
+				members = p
+			}
+		}
+		for j := 0; j < setSamples; j++ {
+			ch, ok := members.random(s.rand)
+			if !ok {
+				break
+			}
+			if c.charIn(index, ch) && !containsRune(possibleChars, ch) {
+				possibleChars = append(possibleChars, ch)
+			}
+		}
+	}
+	return possibleChars, nil
+}
+
+// prevent records that the code from start to stop of the construct at index
+// must not match at the cursor, false when it already does.
+func (r *runner) prevent(index, start, stop int) bool {
+	p := prevent{index: index, pos: r.m.buf.Pos(), start: start, stop: stop}
+	p.back = r.c.rightToLeft(start, stop)
+	if r.c.stable(start, stop) && r.matches(start, stop, p.pos) {
+		return false
+	}
+	r.m.prevents = append(r.m.prevents, p)
+	return true
+}
+
+// excluded reports whether a single char negative lookaround,
+// a boundary next to pos or a greedy loop stopping at pos forbids ch at pos.
+// The constraint is kept as the cause of a failure of the current op.
+func (r *runner) excluded(pos int, ch rune) bool {
+	for _, g := range r.m.greedies {
+		if g.pos == pos && r.c.charIn(g.index, ch) {
+			r.cause = g.index
+			return true
+		}
+	}
+	for _, b := range r.m.boundaries {
+		if !b.allows(r.m.buf, pos, ch) {
+			r.cause = b.index
+			return true
+		}
+	}
+	for _, p := range r.m.prevents {
+		at := p.pos
+		if p.back {
+			at--
+		}
+		if at == pos && p.stop == p.start+2 && r.c.charIn(p.start, ch) {
+			r.cause = p.index
+			return true
+		}
+	}
+	return false
+}
+
+// repeat chooses how many of the n optional repetitions of a quantifier are made,
+// min is the number of required ones.
+func (r *runner) repeat(min, n int) int {
+	var weights []float64
+	if r.s.distribution != nil {
+		weights = r.s.distribution(min, min+n)
+	}
+	return r.choose(n+1, weights)
 }
 
 // create a new generator
 func NewGenerator() *Generator {
-	return &Generator{}
+	return New()
+}
+
+// New creates a generator configured by opts.
+func New(opts ...Option) *Generator {
+	g := &Generator{
+		state: NewState(false, defaultLimit, nil, time.Now().UnixNano()),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
 }
@@ -0,0 +1,494 @@
+package regexp2gen
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/dlclark/regexp2"
+	"github.com/dlclark/regexp2/syntax"
+)
+
+// the kinds of constructs whose instructions can be found back in the pattern
+const (
+	tokenAnchor = iota + 1
+	tokenBoundary
+	tokenRef
+	tokenSetjump
+	tokenBalancing
+	// a char, escape, set or .
+	tokenAtom
+)
+
+// token is a construct of the pattern at offset, text is the construct itself.
+// back is set inside a lookbehind, whose code is in reverse.
+type token struct {
+	offset int
+	kind   int
+	text   string
+	back   bool
+
+	// 单个字符或者字符集编译出来的指令
+	op  syntax.InstOp
+	ch  rune
+	set string
+}
+
+// anchorTokens is the anchor tokens each anchor op can come from.
+var anchorTokens = map[syntax.InstOp][]string{
+	syntax.Bol:       {"^"},
+	syntax.Beginning: {"^", `\A`},
+	syntax.Eol:       {"$"},
+	syntax.EndZ:      {"$", `\Z`},
+	syntax.End:       {"$", `\z`},
+	syntax.Start:     {`\G`},
+}
+
+/*
+offset returns where the instruction at index is in the pattern, -1 when it can not be told.
+
+The syntax tree keeps no positions, so the pattern is split into tokens again and
+the instructions are matched to them in order, each to the next token it can come from.
+Chars, strings and sets are matched by content, as the Multi of ab to the tokens a and b.
+Right to left code is in reverse, it is not matched.
+
+This is best effort: the tokens follow the regexp2 syntax only as far as finding
+the constructs back needs, and an instruction that matches no token gets -1
+instead of a wrong offset. It is only used to report errors.
+
+	a(?=b)\1 -> One a at offset 0, Setjump at offset 1, Ref at offset 6
+*/
+func (p *program) offset(index int) int {
+	if p.offsets == nil {
+		p.offsets = p.matchTokens()
+	}
+	if offset, ok := p.offsets[index]; ok {
+		return offset
+	}
+	return -1
+}
+
+// matchTokens returns the offset of every instruction matched to a token.
+func (p *program) matchTokens() map[int]int {
+	offsets := map[int]int{}
+	if p.pattern == "" || p.RightToLeft {
+		return offsets
+	}
+	tokens := p.tokens()
+
+	next, last, rep := 0, -1, false
+	balancing := []int{}
+	for i := 0; i < len(p.Codes); i += opcodeSize(syntax.InstOp(p.Codes[i])) {
+		if syntax.InstOp(p.Codes[i])&syntax.Rtl != 0 {
+			continue
+		}
+		op := p.op(i)
+		if op == syntax.Capturemark {
+			// 分组在结尾才有 Capturemark，按顺序对应
+			if p.Codes[i+2] != -1 {
+				balancing = append(balancing, i)
+			}
+			continue
+		}
+		// a{2,} -> Onerep Oneloop，两个指令来自同一个字符
+		if rep && p.sameAtom(i, tokens[last]) {
+			offsets[i] = tokens[last].offset
+			rep = false
+			continue
+		}
+		k, n := p.findToken(i, tokens, next)
+		if n == 0 {
+			continue
+		}
+		offsets[i] = tokens[k].offset
+		next, last = k+n, k+n-1
+		switch op {
+		case syntax.Onerep, syntax.Notonerep, syntax.Setrep:
+			rep = true
+		default:
+			rep = false
+		}
+	}
+
+	groups := []int{}
+	for _, t := range tokens {
+		if t.kind == tokenBalancing {
+			groups = append(groups, t.offset)
+		}
+	}
+	if len(groups) == len(balancing) {
+		for k, i := range balancing {
+			offsets[i] = groups[k]
+		}
+	}
+	return offsets
+}
+
+// findToken returns the first token from next the instruction at index comes from
+// and how many tokens it takes, 0 when there is none.
+func (p *program) findToken(index int, tokens []token, next int) (int, int) {
+	for k := next; k < len(tokens); k++ {
+		if n := p.matchToken(index, tokens[k:]); n > 0 {
+			return k, n
+		}
+	}
+	switch p.op(index) {
+	case syntax.Set, syntax.Setrep, syntax.Setloop, syntax.Setlazy:
+		// a|b -> [ab]，对应第一个分支的字符
+		for k := next; k < len(tokens); k++ {
+			if t := tokens[k]; t.kind == tokenAtom && !t.back && t.op == syntax.One && p.charIn(index, t.ch) {
+				return k, 1
+			}
+		}
+	}
+	return 0, 0
+}
+
+// matchToken returns how many of tokens the instruction at index comes from,
+// starting at the first one, 0 when it does not come from there.
+func (p *program) matchToken(index int, tokens []token) int {
+	t := tokens[0]
+	if t.back {
+		return 0
+	}
+	op := p.op(index)
+	switch op {
+	case syntax.Bol, syntax.Beginning, syntax.Eol, syntax.EndZ, syntax.End, syntax.Start:
+		if t.kind == tokenAnchor && containsString(anchorTokens[op], t.text) {
+			return 1
+		}
+	case syntax.Boundary, syntax.ECMABoundary:
+		if t.kind == tokenBoundary && t.text == `\b` {
+			return 1
+		}
+	case syntax.Nonboundary, syntax.NonECMABoundary:
+		if t.kind == tokenBoundary && t.text == `\B` {
+			return 1
+		}
+	case syntax.Ref:
+		if t.kind == tokenRef {
+			return 1
+		}
+	case syntax.Setjump:
+		if t.kind == tokenSetjump {
+			return 1
+		}
+	case syntax.Multi:
+		str := p.Strings[p.Codes[index+1]]
+		if len(tokens) < len(str) {
+			return 0
+		}
+		for k, ch := range str {
+			if t := tokens[k]; t.kind != tokenAtom || t.back || !p.sameRune(index, t, ch) {
+				return 0
+			}
+		}
+		return len(str)
+	default:
+		if p.sameAtom(index, t) {
+			return 1
+		}
+	}
+	return 0
+}
+
+// sameAtom reports whether the char instruction at index comes from the atom t.
+func (p *program) sameAtom(index int, t token) bool {
+	if t.kind != tokenAtom {
+		return false
+	}
+	switch p.op(index) {
+	case syntax.One, syntax.Onerep, syntax.Oneloop, syntax.Onelazy:
+		return p.sameRune(index, t, rune(p.Codes[index+1]))
+	case syntax.Notone, syntax.Notonerep, syntax.Notoneloop, syntax.Notonelazy:
+		return t.op == syntax.Notone && t.ch == rune(p.Codes[index+1])
+	case syntax.Set, syntax.Setrep, syntax.Setloop, syntax.Setlazy:
+		return t.op == syntax.Set && t.set == p.Sets[p.Codes[index+1]].String()
+	}
+	return false
+}
+
+// sameRune reports whether the atom t is the char ch of the instruction at index.
+func (p *program) sameRune(index int, t token, ch rune) bool {
+	if t.op != syntax.One {
+		return false
+	}
+	// 忽略大小写的指令里是小写
+	return t.ch == ch || p.ci(index) && unicode.ToLower(t.ch) == ch
+}
+
+// tokens splits the pattern into the tokens instructions can come from.
+func (p *program) tokens() []token {
+	rs := []rune(p.pattern)
+	options := p.options &^ regexp2.RightToLeft
+	result := []token{}
+	// 每层分组是否在后顾里，和分组外面的选项
+	type frame struct {
+		back    bool
+		options regexp2.RegexOptions
+	}
+	groups := []frame{}
+	back := func() bool {
+		return len(groups) > 0 && groups[len(groups)-1].back
+	}
+	push := func(lookbehind bool) {
+		groups = append(groups, frame{lookbehind || back(), options})
+	}
+	// 按字节记录位置，和 regexp2 的错误信息一样
+	offset := func(i int) int {
+		return len(string(rs[:i]))
+	}
+	add := func(i, end, kind int) {
+		result = append(result, token{offset: offset(i), kind: kind, text: string(rs[i:end]), back: back()})
+	}
+	atom := func(i, end int) {
+		t := token{offset: offset(i), kind: tokenAtom, text: string(rs[i:end]), back: back()}
+		t.op, t.ch, t.set = compileAtom(t.text, options)
+		result = append(result, t)
+	}
+
+	for i := 0; i < len(rs); i++ {
+		ch := rs[i]
+		switch {
+		case ch == '\\':
+			end := escapeEnd(rs, i)
+			switch text := string(rs[i:end]); {
+			case text == `\A` || text == `\z` || text == `\Z` || text == `\G`:
+				add(i, end, tokenAnchor)
+			case text == `\b` || text == `\B`:
+				add(i, end, tokenBoundary)
+			case len(text) > 1 && (text[1] >= '1' && text[1] <= '9' || text[1] == 'k'):
+				add(i, end, tokenRef)
+			default:
+				atom(i, end)
+			}
+			i = end - 1
+		case ch == '^' || ch == '$':
+			add(i, i+1, tokenAnchor)
+		case ch == '[':
+			end := classEnd(rs, i) + 1
+			if end > len(rs) {
+				end = len(rs)
+			}
+			atom(i, end)
+			i = end - 1
+		case ch == '(':
+			i = group(rs, i, &options, push, func(kind int, end int) {
+				add(i, end, kind)
+			})
+		case ch == ')':
+			if len(groups) > 0 {
+				options = groups[len(groups)-1].options
+				groups = groups[:len(groups)-1]
+			}
+		case ch == '*' || ch == '+' || ch == '?' || ch == '|':
+		case ch == '{' && quantifierEnd(rs, i) > i:
+			i = quantifierEnd(rs, i)
+		case options&regexp2.IgnorePatternWhitespace != 0 && unicode.IsSpace(ch):
+		case options&regexp2.IgnorePatternWhitespace != 0 && ch == '#':
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+		default:
+			atom(i, i+1)
+		}
+	}
+	return result
+}
+
+// group reads the start of the group at i, calling add for its token and push for
+// the group it opens. Inline options are set in options. It returns the index of the last rune read.
+func group(rs []rune, i int, options *regexp2.RegexOptions, push func(bool), add func(kind, end int)) int {
+	rest := string(rs[i+1:])
+	switch {
+	case strings.HasPrefix(rest, "?#"):
+		// 注释
+		for i < len(rs) && rs[i] != ')' {
+			i++
+		}
+		return i
+	case strings.HasPrefix(rest, "?<=") || strings.HasPrefix(rest, "?<!"):
+		add(tokenSetjump, i+4)
+		push(true)
+		return i + 3
+	case strings.HasPrefix(rest, "?=") || strings.HasPrefix(rest, "?!") || strings.HasPrefix(rest, "?>"):
+		add(tokenSetjump, i+3)
+		push(false)
+		return i + 2
+	case strings.HasPrefix(rest, "?("):
+		add(tokenSetjump, i+3)
+		push(false)
+		// (?(1)yes|no) (?(name)yes|no) 的条件不是表达式
+		end := i + 3
+		for end < len(rs) && (rs[end] == '_' || unicode.IsLetter(rs[end]) || unicode.IsDigit(rs[end])) {
+			end++
+		}
+		if end < len(rs) && rs[end] == ')' && end > i+3 {
+			return end
+		}
+		return i + 1
+	case strings.HasPrefix(rest, "?<") || strings.HasPrefix(rest, "?'"):
+		// 命名分组 (?<name>...)，平衡组 (?<name-other>...)
+		close := '>'
+		if rs[i+2] == '\'' {
+			close = '\''
+		}
+		end := i + 3
+		for end < len(rs) && rs[end] != close {
+			end++
+		}
+		if strings.ContainsRune(string(rs[i+3:minInt(end, len(rs))]), '-') {
+			add(tokenBalancing, minInt(end+1, len(rs)))
+		}
+		push(false)
+		return end
+	case strings.HasPrefix(rest, "?"):
+		// (?:...) (?i-s:...) 的选项在分组里，(?i) 的选项到所在的分组结束
+		end, on, set := i+2, true, *options
+		for end < len(rs) && rs[end] != ':' && rs[end] != ')' {
+			if rs[end] == '-' {
+				on = false
+			} else if option, ok := inlineOptions[rs[end]]; ok && on {
+				set |= option
+			} else if ok {
+				set &^= option
+			}
+			end++
+		}
+		if end < len(rs) && rs[end] == ':' {
+			push(false)
+		}
+		*options = set
+		return end
+	}
+	push(false)
+	return i
+}
+
+// inlineOptions is the options that change how the atoms compile.
+var inlineOptions = map[rune]regexp2.RegexOptions{
+	'i': regexp2.IgnoreCase,
+	's': regexp2.Singleline,
+	'x': regexp2.IgnorePatternWhitespace,
+}
+
+// compileAtom returns the instruction the atom text compiles to alone,
+// 0 when it is not a single char or set.
+func compileAtom(text string, options regexp2.RegexOptions) (syntax.InstOp, rune, string) {
+	if len([]rune(text)) == 1 && text != "." {
+		return syntax.One, []rune(text)[0], ""
+	}
+	tree, err := syntax.Parse(text, syntax.RegexOptions(options))
+	if err != nil {
+		return 0, 0, ""
+	}
+	c, err := syntax.Write(tree)
+	if err != nil {
+		return 0, 0, ""
+	}
+	for i := 0; i < len(c.Codes); i += opcodeSize(syntax.InstOp(c.Codes[i])) {
+		switch syntax.InstOp(c.Codes[i]) & syntax.Mask {
+		case syntax.One:
+			return syntax.One, rune(c.Codes[i+1]), ""
+		case syntax.Notone:
+			return syntax.Notone, rune(c.Codes[i+1]), ""
+		case syntax.Set:
+			return syntax.Set, 0, c.Sets[c.Codes[i+1]].String()
+		case syntax.Multi:
+			return 0, 0, ""
+		}
+	}
+	return 0, 0, ""
+}
+
+// escapeEnd returns the index after the escape starting at i.
+func escapeEnd(rs []rune, i int) int {
+	end := i + 2
+	if end > len(rs) {
+		return len(rs)
+	}
+	until := func(close rune) int {
+		for end < len(rs) && rs[end] != close {
+			end++
+		}
+		return minInt(end+1, len(rs))
+	}
+	digits := func(n int, ok func(rune) bool) int {
+		for k := 0; k < n && end < len(rs) && ok(rs[end]); k++ {
+			end++
+		}
+		return end
+	}
+	isHex := func(ch rune) bool {
+		return unicode.Is(unicode.ASCII_Hex_Digit, ch)
+	}
+	switch rs[i+1] {
+	case 'p', 'P':
+		return until('}')
+	case 'x':
+		if end < len(rs) && rs[end] == '{' {
+			return until('}')
+		}
+		return digits(2, isHex)
+	case 'u':
+		return digits(4, isHex)
+	case 'c':
+		return digits(1, func(rune) bool { return true })
+	case '0':
+		return digits(2, func(ch rune) bool { return ch >= '0' && ch <= '7' })
+	case 'k':
+		if end < len(rs) && (rs[end] == '<' || rs[end] == '\'') {
+			close := '>'
+			if rs[end] == '\'' {
+				close = '\''
+			}
+			return until(close)
+		}
+	}
+	if rs[i+1] >= '1' && rs[i+1] <= '9' {
+		return digits(len(rs), unicode.IsDigit)
+	}
+	return end
+}
+
+// quantifierEnd returns the index of the } of the quantifier {n}, {n,} or {n,m} at i,
+// i when the { is a char.
+func quantifierEnd(rs []rune, i int) int {
+	end := i + 1
+	digits := 0
+	for end < len(rs) && (unicode.IsDigit(rs[end]) || rs[end] == ',') {
+		if rs[end] != ',' {
+			digits++
+		}
+		end++
+	}
+	if end < len(rs) && rs[end] == '}' && digits > 0 && unicode.IsDigit(rs[i+1]) {
+		return end
+	}
+	return i
+}
+
+// classEnd returns the index of the ] closing the char class starting at start.
+func classEnd(rs []rune, start int) int {
+	i := start + 1
+	if i < len(rs) && rs[i] == '^' {
+		i++
+	}
+	// 开头的 ] 是字符
+	if i < len(rs) && rs[i] == ']' {
+		i++
+	}
+	for ; i < len(rs); i++ {
+		switch rs[i] {
+		case '\\':
+			i++
+		case '[':
+			// 减去的字符集，例如 [a-z-[aeiou]]
+			if rs[i-1] == '-' {
+				i = classEnd(rs, i)
+			}
+		case ']':
+			return i
+		}
+	}
+	return i
+}
@@ -0,0 +1,172 @@
+package regexp2gen
+
+import (
+	"math/rand"
+	"os"
+	"strings"
+
+	"github.com/dlclark/regexp2"
+)
+
+// Option configures a Generator created by New.
+type Option func(*Generator)
+
+// WithSeed seeds the random source, so the same pattern generates the same strings.
+func WithSeed(seed int64) Option {
+	return func(g *Generator) {
+		g.state.rand = rand.New(rand.NewSource(seed))
+	}
+}
+
+// WithAlphabet sets the runes used for `.`, negated characters and sets
+// that have no alphabet of their own, see WithClassAlphabet.
+func WithAlphabet(chars []rune) Option {
+	return func(g *Generator) {
+		if len(chars) > 0 {
+			g.state.chars = chars
+		}
+	}
+}
+
+// WithPrintable restricts the runes a char set or unicode category such as \p{L}
+// picks outside the alphabet to assigned, printable ones. A set without such
+// runes, as \p{Cc}, still picks from all of its runes.
+func WithPrintable(printable bool) Option {
+	return func(g *Generator) {
+		g.state.printable = printable
+	}
+}
+
+// WithLiteralAlphabet adds the literal runes of the pattern and the ends of
+// the ranges of its sets to the alphabet, so . and negated sets often write
+// the delimiters the pattern looks for, as the , of [^,]*,.
+func WithLiteralAlphabet(literal bool) Option {
+	return func(g *Generator) {
+		g.state.literals = literal
+	}
+}
+
+// WithBoundary sets the non-word rune written next to a \b when the alphabet has none that fits,
+// the same as WithClassAlphabet(ClassBoundary, []rune{r}).
+func WithBoundary(r rune) Option {
+	return WithClassAlphabet(ClassBoundary, []rune{r})
+}
+
+/*
+WithClassAlphabet sets the runes a class of char instructions uses instead of
+the alphabet, weights are by rune and missing ones count as 1.
+
+	// \s is a tab or a no-break space 10% of the time
+	WithClassAlphabet(ClassSpace, []rune(" \t\u00a0"), 18, 1, 1)
+*/
+func WithClassAlphabet(class Class, chars []rune, weights ...float64) Option {
+	return func(g *Generator) {
+		if len(chars) == 0 {
+			return
+		}
+		if g.state.classes == nil {
+			g.state.classes = make(map[Class]alphabet)
+		}
+		g.state.classes[class] = newAlphabet(chars, weights)
+	}
+}
+
+// WithLineEnding sets the line break written where the pattern allows one,
+// "\n" by default or "\r\n". It has to end with "\n" for ^ and $ to see it.
+func WithLineEnding(ending string) Option {
+	return func(g *Generator) {
+		if strings.HasSuffix(ending, "\n") {
+			g.state.lineEnding = []rune(ending)
+		}
+	}
+}
+
+// WithLimit sets the repetition limit of unbounded quantifiers.
+func WithLimit(limit int) Option {
+	return func(g *Generator) {
+		g.state.limit = limit
+	}
+}
+
+// WithDebug writes the trace of every generation to stdout, see WithTracer.
+// WithDebug(false) keeps the tracer set by other options.
+func WithDebug(debug bool) Option {
+	return func(g *Generator) {
+		if debug {
+			g.state.tracer = NewWriterTracer(os.Stdout)
+		}
+	}
+}
+
+// WithTracer sends the steps of every generation to t, as NewWriterTracer(w) to write them to w.
+// The lookarounds checked on the finished text are not traced.
+func WithTracer(t Tracer) Option {
+	return func(g *Generator) {
+		g.state.tracer = t
+	}
+}
+
+// WithRegexOptions sets the regexp2 options used to parse and verify patterns.
+func WithRegexOptions(op regexp2.RegexOptions) Option {
+	return func(g *Generator) {
+		g.options = op
+	}
+}
+
+// WithMaxAttempts sets how many times generation starts over before giving up.
+func WithMaxAttempts(n int) Option {
+	return func(g *Generator) {
+		if n > 0 {
+			g.state.attempts = n
+		}
+	}
+}
+
+// WithMaxBacktracks sets how many times one attempt goes back to an earlier random choice.
+func WithMaxBacktracks(n int) Option {
+	return func(g *Generator) {
+		if n >= 0 {
+			g.state.backtracks = n
+		}
+	}
+}
+
+// WithBranchWeights sets the weights of the branches of the n-th alternation
+// of the pattern, counted from 0. Branches without a weight count as 1 and
+// a branch weighted 0 is only used when the others fail.
+func WithBranchWeights(n int, weights ...float64) Option {
+	return func(g *Generator) {
+		if g.state.branchWeights == nil {
+			g.state.branchWeights = make(map[int][]float64)
+		}
+		g.state.branchWeights[n] = weights
+	}
+}
+
+// WithDistribution sets how repetition counts of quantifiers are drawn, uniform by default.
+func WithDistribution(d Distribution) Option {
+	return func(g *Generator) {
+		g.state.distribution = d
+	}
+}
+
+// WithMaxDepth sets how many captures a group can hold at once,
+// which bounds the nesting of balancing groups such as (?<open>\()...(?<-open>\)).
+// 0, the default, means no limit.
+func WithMaxDepth(n int) Option {
+	return func(g *Generator) {
+		if n >= 0 {
+			g.state.depth = n
+		}
+	}
+}
+
+// WithTrials makes Analyze run n trial generations with their own seed and
+// estimate the chance that Generate succeeds from them. 0, the default, runs none.
+func WithTrials(n int) Option {
+	return func(g *Generator) {
+		if n >= 0 {
+			g.trials = n
+		}
+	}
+}
@@ -0,0 +1,546 @@
+package regexp2gen
+
+import (
+	"unicode"
+
+	"github.com/dlclark/regexp2"
+	"github.com/dlclark/regexp2/syntax"
+)
+
+// program is the compiled code with the lookups the generator needs.
+type program struct {
+	*syntax.Code
+	options regexp2.RegexOptions
+	// source of the code, empty when unknown
+	pattern string
+
+	// start of the previous instruction, by instruction start
+	prev map[int]int
+	// ordinal of each alternation in the pattern, by its first Lazybranch
+	alternations map[int]int
+	// runes of each char set, by set index
+	members map[int]runeRanges
+	// runes written by the pattern itself, nil until asked for
+	literals []rune
+	// Setjump of every Forejump
+	scopes map[int]int
+	// Setjump of every atomic group
+	atomics []int
+	// offset in the pattern by instruction start, nil until asked for
+	offsets map[int]int
+	// fewest runes of the match before each instruction, nil until asked for
+	widths map[int]int
+}
+
+// compile parses the pattern re into a program.
+func compile(re string, options regexp2.RegexOptions) (*program, error) {
+	// 行内的选项由编译后的代码体现：(?i) 是指令的 Ci 标志，(?s) (?m) 换成了不同的指令，
+	// (?n) (?x) 在解析时处理，生成时只有不能写在行内的 RE2 ECMAScript 还要看 options
+	tree, err := syntax.Parse(re, syntax.RegexOptions(options))
+	if err != nil {
+		return nil, err
+	}
+	c, err := syntax.Write(tree)
+	if err != nil {
+		return nil, err
+	}
+	p := newProgram(c, options)
+	p.pattern = re
+	return p, nil
+}
+
+func newProgram(c *syntax.Code, options regexp2.RegexOptions) *program {
+	p := &program{
+		Code:         c,
+		options:      options,
+		prev:         make(map[int]int),
+		alternations: make(map[int]int),
+		members:      make(map[int]runeRanges),
+		scopes:       make(map[int]int),
+	}
+
+	last := -1
+	for i := 0; i < len(c.Codes); i += opcodeSize(syntax.InstOp(c.Codes[i])) {
+		if last >= 0 {
+			p.prev[i] = last
+		}
+		last = i
+	}
+
+	// 条件结构的两个分支各有一个 Forejump
+	type scope struct {
+		setjump int
+		left    int
+	}
+	scopes := []scope{}
+	for i := 0; i < len(c.Codes); i += opcodeSize(syntax.InstOp(c.Codes[i])) {
+		switch p.op(i) {
+		case syntax.Setjump:
+			left := 1
+			if p.op(i+1) == syntax.Lazybranch && p.op(i+3) == syntax.Testref {
+				left = 2
+			} else if p.op(i+1) == syntax.Setmark && p.op(i+2) == syntax.Lazybranch {
+				if _, ok := p.condition(i + 2); ok {
+					left = 2
+				}
+			}
+			scopes = append(scopes, scope{setjump: i, left: left})
+		case syntax.Forejump:
+			l := len(scopes)
+			if l == 0 {
+				break
+			}
+			top := &scopes[l-1]
+			p.scopes[i] = top.setjump
+			if prev := p.op(p.prev[i]); top.left == 1 && prev != syntax.Getmark && prev != syntax.Backjump {
+				// 不是环视也不是条件
+				p.atomics = append(p.atomics, top.setjump)
+			}
+			top.left--
+			if top.left == 0 {
+				scopes = scopes[:l-1]
+			}
+		}
+	}
+
+	// 同一个选择结构中后续分支的 Lazybranch 不再单独计数
+	inner := make(map[int]bool)
+	for i := 0; i < len(c.Codes); i += opcodeSize(syntax.InstOp(c.Codes[i])) {
+		if p.op(i) != syntax.Lazybranch || inner[i] || p.op(i+2) == syntax.Testref {
+			continue
+		}
+		if _, ok := p.condition(i); ok {
+			continue
+		}
+		starts := p.alternatives(i)
+		if starts == nil {
+			continue
+		}
+		p.alternations[i] = len(p.alternations)
+		for _, start := range starts[1 : len(starts)-1] {
+			inner[start-2] = true
+		}
+	}
+	return p
+}
+
+// op returns the instruction at index without the modifier bits.
+func (p *program) op(index int) syntax.InstOp {
+	return syntax.InstOp(p.Codes[index]) & syntax.Mask
+}
+
+/*
+alternatives returns the start of every branch of the alternation whose first
+Lazybranch is at index, or nil when that Lazybranch is not an alternation.
+
+	ab|cd|ef ->
+	Lazybranch(A1) Multi(ab) Goto(E) A1: Lazybranch(A2) Multi(cd) Goto(E) A2: Multi(ef) E:
+*/
+func (p *program) alternatives(index int) []int {
+	addr := p.Codes[index+1]
+	end, ok := p.branchEnd(addr)
+	if !ok {
+		return nil
+	}
+
+	starts := []int{index + 2}
+	for p.op(addr) == syntax.Lazybranch {
+		next := p.Codes[addr+1]
+		if e, ok := p.branchEnd(next); !ok || e != end {
+			break
+		}
+		starts = append(starts, addr+2)
+		addr = next
+	}
+	return append(starts, addr)
+}
+
+// branchEnd returns where the branch ending right before addr jumps to.
+func (p *program) branchEnd(addr int) (int, bool) {
+	prev, ok := p.prev[addr]
+	if !ok || p.op(prev) != syntax.Goto {
+		return 0, false
+	}
+	return p.Codes[prev+1], true
+}
+
+// prevent returns the Forejump ending the negative lookahead started by the Setjump at index.
+//
+//	(?!...) -> Setjump Lazybranch(A) ... Backjump A: Forejump
+func (p *program) prevent(index int) (int, bool) {
+	if p.op(index+1) != syntax.Lazybranch {
+		return 0, false
+	}
+	end := p.Codes[index+2]
+	if prev, ok := p.prev[end]; !ok || p.op(prev) != syntax.Backjump || p.op(end) != syntax.Forejump {
+		return 0, false
+	}
+	return end, true
+}
+
+/*
+condition returns the Getmark ending the condition of the expression conditional
+whose Lazybranch is at index.
+
+	(?(cond)yes|no) ->
+	Setjump Setmark Lazybranch(A) cond Getmark Forejump yes Goto(E) A: Getmark Forejump no E:
+*/
+func (p *program) condition(index int) (int, bool) {
+	setmark, ok := p.prev[index]
+	if !ok || p.op(setmark) != syntax.Setmark {
+		return 0, false
+	}
+	if setjump, ok := p.prev[setmark]; !ok || p.op(setjump) != syntax.Setjump {
+		return 0, false
+	}
+	addr := p.Codes[index+1]
+	if p.op(addr) != syntax.Getmark || p.op(addr+1) != syntax.Forejump {
+		return 0, false
+	}
+	// (?=a|) 的 Goto 直接跳到 A
+	if end, ok := p.branchEnd(addr); !ok || end == addr {
+		return 0, false
+	}
+
+	// Setmark 和 Getmark Capturemark Branchmark 成对出现
+	depth := 1
+	for i := index + 2; i < addr; i += opcodeSize(syntax.InstOp(p.Codes[i])) {
+		switch p.op(i) {
+		case syntax.Setmark, syntax.Nullmark:
+			depth++
+		case syntax.Capturemark, syntax.Branchmark, syntax.Lazybranchmark:
+			depth--
+		case syntax.Getmark:
+			depth--
+			if depth == 0 {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// balancing returns the group whose captures the code from start to stop pops
+// with a balancing group, when it does not capture the group itself.
+func (p *program) balancing(start, stop int) (int, bool) {
+	uncapture := -1
+	for i := start; i < stop; i += opcodeSize(syntax.InstOp(p.Codes[i])) {
+		if p.op(i) == syntax.Capturemark && p.Codes[i+2] != -1 {
+			uncapture = p.Codes[i+2]
+			break
+		}
+	}
+	if uncapture == -1 {
+		return 0, false
+	}
+	for i := start; i < stop; i += opcodeSize(syntax.InstOp(p.Codes[i])) {
+		if p.op(i) == syntax.Capturemark && p.Codes[i+1] == uncapture {
+			return 0, false
+		}
+	}
+	return uncapture, true
+}
+
+// stable reports whether a match of the code from start to stop
+// stays a match whatever text is added around it.
+func (p *program) stable(start, stop int) bool {
+	for i := start; i < stop; i += opcodeSize(syntax.InstOp(p.Codes[i])) {
+		switch p.op(i) {
+		case syntax.Bol, syntax.Beginning, syntax.Start,
+			syntax.Eol, syntax.EndZ, syntax.End, syntax.Boundary, syntax.Nonboundary,
+			syntax.ECMABoundary, syntax.NonECMABoundary, syntax.Setjump:
+			return false
+		}
+	}
+	return true
+}
+
+/*
+steps returns the instructions the match goes on with after the one at index
+and how many runes it takes on the way. The code of lookarounds takes no runes of
+the match and is stepped over, and so is the condition of a conditional.
+*/
+func (p *program) steps(index int) [][2]int {
+	size := opcodeSize(syntax.InstOp(p.Codes[index]))
+	switch p.op(index) {
+	case syntax.One, syntax.Notone, syntax.Set:
+		return [][2]int{{index + size, 1}}
+	case syntax.Onerep, syntax.Notonerep, syntax.Setrep:
+		return [][2]int{{index + size, p.Codes[index+2]}}
+	case syntax.Multi:
+		return [][2]int{{index + size, len(p.Strings[p.Codes[index+1]])}}
+	case syntax.Goto:
+		return [][2]int{{p.Codes[index+1], 0}}
+	case syntax.Lazybranch:
+		if stop, ok := p.condition(index); ok {
+			// 条件和前瞻一样不占字符
+			return [][2]int{{stop, 0}, {p.Codes[index+1], 0}}
+		}
+		return [][2]int{{index + size, 0}, {p.Codes[index+1], 0}}
+	case syntax.Branchmark, syntax.Lazybranchmark, syntax.Branchcount, syntax.Lazybranchcount:
+		return [][2]int{{index + size, 0}, {p.Codes[index+1], 0}}
+	case syntax.Setjump:
+		// 条件结构有两个 Forejump，原子组的内容占字符
+		ends := []int{}
+		for forejump, setjump := range p.scopes {
+			if setjump == index {
+				ends = append(ends, forejump)
+			}
+		}
+		if len(ends) == 1 && !p.atomic(index) {
+			return [][2]int{{ends[0] + 1, 0}}
+		}
+	case syntax.Stop, syntax.Backjump:
+		return nil
+	}
+	return [][2]int{{index + size, 0}}
+}
+
+/*
+minWidths returns the fewest runes the match takes from the instruction at from
+to each instruction it gets to, by instruction start. The match does not go on
+from the instructions that are blocked.
+
+	a*b(?=c)\G -> Oneloop(a) and One(b) at 0 runes, Setjump and Start at 1
+*/
+func (p *program) minWidths(from int, blocked func(int) bool) map[int]int {
+	widths := map[int]int{from: 0}
+	queue := []int{from}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		if blocked != nil && blocked(i) {
+			continue
+		}
+		for _, s := range p.steps(i) {
+			if w, ok := widths[s[0]]; s[0] < len(p.Codes) && (!ok || widths[i]+s[1] < w) {
+				widths[s[0]] = widths[i] + s[1]
+				queue = append(queue, s[0])
+			}
+		}
+	}
+	return widths
+}
+
+// matchEnd returns the Capturemark of group 0 that ends a match, -1 when there is none.
+func (p *program) matchEnd() int {
+	end := -1
+	for i := 0; i < len(p.Codes); i += opcodeSize(syntax.InstOp(p.Codes[i])) {
+		if p.op(i) == syntax.Capturemark && p.Codes[i+1] == 0 {
+			end = i
+		}
+	}
+	return end
+}
+
+// afterText reports whether the match has taken runes on every way to the
+// instruction at index, so \A or \G can not hold there, as in a\G.
+// Right to left code is not told.
+func (p *program) afterText(index int) bool {
+	if p.RightToLeft {
+		return false
+	}
+	if p.widths == nil {
+		p.widths = p.minWidths(0, nil)
+	}
+	w, ok := p.widths[index]
+	return ok && w > 0
+}
+
+// beforeText reports whether the match takes runes on every way from the
+// instruction at index to its end, so \z can not hold there, as in a\zb.
+// Right to left code is not told.
+func (p *program) beforeText(index int) bool {
+	if p.RightToLeft {
+		return false
+	}
+	if p.widths == nil {
+		p.widths = p.minWidths(0, nil)
+	}
+	if _, ok := p.widths[index]; !ok {
+		// 环视里的指令
+		return false
+	}
+	w, ok := p.minWidths(index, nil)[p.matchEnd()]
+	return ok && w > 0
+}
+
+// ci reports whether the instruction at index ignores case.
+func (p *program) ci(index int) bool {
+	return syntax.InstOp(p.Codes[index])&syntax.Ci != 0
+}
+
+// charIn reports whether the char instruction at index matches ch.
+func (p *program) charIn(index int, ch rune) bool {
+	if p.ci(index) {
+		// 忽略大小写时编译后的字符都是小写
+		ch = unicode.ToLower(ch)
+	}
+	switch p.op(index) {
+	case syntax.One, syntax.Onerep, syntax.Oneloop, syntax.Onelazy:
+		return ch == rune(p.Codes[index+1])
+	case syntax.Notone, syntax.Notonerep, syntax.Notoneloop, syntax.Notonelazy:
+		return ch != rune(p.Codes[index+1])
+	case syntax.Set, syntax.Setrep, syntax.Setloop, syntax.Setlazy:
+		return p.Sets[p.Codes[index+1]].CharIn(ch)
+	}
+	return false
+}
+
+// charSet returns every rune the char instruction at index matches,
+// before ignoring case.
+func (p *program) charSet(index int) runeRanges {
+	switch p.op(index) {
+	case syntax.One, syntax.Onerep, syntax.Oneloop, syntax.Onelazy:
+		ch := rune(p.Codes[index+1])
+		return runeRanges{{ch, ch}}
+	case syntax.Notone, syntax.Notonerep, syntax.Notoneloop, syntax.Notonelazy:
+		ch := rune(p.Codes[index+1])
+		return runeRanges{{ch, ch}}.complement().subtract(surrogates)
+	}
+	return p.setRanges(p.Codes[index+1])
+}
+
+// setRanges returns the runes of the set at index i of Sets.
+func (p *program) setRanges(i int) runeRanges {
+	if rs, ok := p.members[i]; ok {
+		return rs
+	}
+	rs := members(p.Sets[i])
+	p.members[i] = rs
+	return rs
+}
+
+/*
+atomicLoop returns the Setjump of the atomic group or lookaround that the char
+loop at index ends, with only code that can match empty between. regexp2 does not
+backtrack into them, so the loop matches as many runes as it can, or as few
+when lazy. In a lookaround that only shows through a capture ending there.
+
+	(?>a+)    -> Setjump Onerep Oneloop Forejump
+	(?=(\d+)) -> Setjump Setmark Setmark Setrep Setloop Capturemark Getmark Forejump
+*/
+func (p *program) atomicLoop(index int) (int, bool) {
+	switch p.op(index) {
+	case syntax.Oneloop, syntax.Notoneloop, syntax.Setloop, syntax.Onelazy, syntax.Notonelazy, syntax.Setlazy:
+	default:
+		return 0, false
+	}
+	captured := false
+	for i := index + opcodeSize(syntax.InstOp(p.Codes[index])); i < len(p.Codes); i += opcodeSize(syntax.InstOp(p.Codes[i])) {
+		switch p.op(i) {
+		case syntax.Capturemark:
+			captured = true
+		case syntax.Setmark, syntax.Nullmark,
+			syntax.Oneloop, syntax.Notoneloop, syntax.Setloop, syntax.Onelazy, syntax.Notonelazy, syntax.Setlazy:
+		case syntax.Getmark:
+			if !captured || p.op(i+1) != syntax.Forejump {
+				return 0, false
+			}
+			setjump, ok := p.scopes[i+1]
+			return setjump, ok
+		case syntax.Forejump:
+			setjump, ok := p.scopes[i]
+			return setjump, ok && p.atomic(setjump)
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// atomic reports whether the Setjump at index starts an atomic group.
+//
+//	(?>...) -> Setjump ... Forejump
+func (p *program) atomic(index int) bool {
+	for _, i := range p.atomics {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+literalRunes returns the runes of the literal strings and chars of the pattern
+and the ends of the ranges of its small sets, the runes the pattern cares about.
+
+	"[^"]*",[0-9]+ -> " , 0 9
+*/
+func (p *program) literalRunes() []rune {
+	if p.literals != nil {
+		return p.literals
+	}
+	p.literals = []rune{}
+	add := func(ch rune) {
+		// 字符集的边界可能是控制字符，例如 [^\x00-\x1f]
+		if unicode.IsGraphic(ch) && !containsRune(p.literals, ch) {
+			p.literals = append(p.literals, ch)
+		}
+	}
+	for _, str := range p.Strings {
+		for _, ch := range str {
+			add(ch)
+		}
+	}
+	for i := 0; i < len(p.Codes); i += opcodeSize(syntax.InstOp(p.Codes[i])) {
+		switch p.op(i) {
+		case syntax.One, syntax.Onerep, syntax.Oneloop, syntax.Onelazy:
+			add(rune(p.Codes[i+1]))
+		}
+	}
+	for i := range p.Sets {
+		// 只取小的字符集的边界，例如 [a-f0-9] 的 a f 0 9，\d 这样的类别太多了，
+		// 也不用去查 unicode 表
+		rs, _, ok := parseSet([]rune(p.Sets[i].String()), false)
+		if ok && p.Sets[i].IsNegated() {
+			rs = rs.complement()
+		}
+		if ok && len(rs) <= literalRanges {
+			for _, r := range rs {
+				add(r.first)
+				add(r.last)
+			}
+		}
+	}
+	return p.literals
+}
+
+// newline reports whether the last rune written by the char instruction at index
+// has to be a newline, as the code goes on with ^, or with $ when right to left.
+func (p *program) newline(index int, rtl bool) bool {
+	for i := index + opcodeSize(syntax.InstOp(p.Codes[index])); i < len(p.Codes); i += opcodeSize(syntax.InstOp(p.Codes[i])) {
+		switch p.op(i) {
+		case syntax.Setmark, syntax.Nullmark, syntax.Capturemark:
+			continue
+		case syntax.Bol:
+			return !rtl
+		case syntax.Eol:
+			return rtl
+		}
+		return false
+	}
+	return false
+}
+
+// anyChar reports whether the char instruction at index matches every rune,
+// as . does with Singleline.
+func (p *program) anyChar(index int) bool {
+	switch p.op(index) {
+	case syntax.Set, syntax.Setrep, syntax.Setloop, syntax.Setlazy:
+		return p.Sets[p.Codes[index+1]].String() == syntax.AnyClass().String()
+	}
+	return false
+}
+
+// rightToLeft reports whether the code from start to stop is compiled right to left,
+// as the body of a lookbehind or a RightToLeft pattern is.
+// Only char instructions carry the direction, code without them counts as left to right.
+func (p *program) rightToLeft(start, stop int) bool {
+	for i := start; i < stop; i += opcodeSize(syntax.InstOp(p.Codes[i])) {
+		if p.op(i) <= syntax.Ref {
+			return syntax.InstOp(p.Codes[i])&syntax.Rtl != 0
+		}
+	}
+	return false
+}
@@ -1,6 +1,11 @@
 package regexp2gen
 
-import "math/rand"
+import (
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+)
 
 const printableChars = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-_ \\\n\r"
 
@@ -8,8 +13,17 @@ var printableCharsNoNL = printableChars[:len(printableChars)-2]
 
 var defaultBoundary = ' '
 
-type state struct {
-	debug bool
+const defaultLimit = 8
+
+const (
+	defaultAttempts   = 10
+	defaultBacktracks = 64
+)
+
+// State holds the random source and the alphabet used while generating.
+type State struct {
+	// receives the steps of the generation, nil for none
+	tracer Tracer
 
 	rand *rand.Rand
 
@@ -17,10 +31,31 @@ type state struct {
 	// use for .
 	chars []rune
 
-	boundary rune
+	// class -> runes used instead of chars
+	classes map[Class]alphabet
+	// runes outside the alphabet are assigned and printable
+	printable bool
+	// add the literal runes of the pattern to the alphabet
+	literals bool
+	// line break written where the pattern allows one, ends with \n
+	lineEnding []rune
+
+	// weights of the repetition counts of quantifiers, nil for uniform
+	distribution Distribution
+
+	// alternation ordinal -> weight of each branch
+	branchWeights map[int][]float64
+
+	// max captures a group holds at once, 0 for no limit
+	depth int
+
+	// max generation attempts, each one starts with fresh random decisions
+	attempts int
+	// max backtracks to earlier choice points in one attempt
+	backtracks int
 }
 
-func (s *state) randomRunes(chars []rune, length int) []rune {
+func (s *State) randomRunes(chars []rune, length int) []rune {
 	result := []rune{}
 	for j := 0; j < length; j++ {
 		r := chars[s.rand.Intn(len(chars))]
@@ -29,18 +64,83 @@ func (s *state) randomRunes(chars []rune, length int) []rune {
 	return result
 }
 
-func NewState(debug bool, limit int, chars []rune, seed int64) *state {
+// randomRune picks one of chars, weights by rune may be nil for a uniform pick.
+func (s *State) randomRune(chars []rune, weights map[rune]float64) rune {
+	if weights == nil {
+		return s.randomRunes(chars, 1)[0]
+	}
+	total := 0.0
+	for _, ch := range chars {
+		total += weight(weights, ch)
+	}
+	if total <= 0 {
+		return s.randomRunes(chars, 1)[0]
+	}
+	k := s.rand.Float64() * total
+	for _, ch := range chars {
+		if k -= weight(weights, ch); k < 0 {
+			return ch
+		}
+	}
+	return chars[len(chars)-1]
+}
+
+func weight(weights map[rune]float64, ch rune) float64 {
+	if w, ok := weights[ch]; ok {
+		return w
+	}
+	return 1
+}
+
+// randomOrder shuffles 0..n-1, an option with a larger weight tends to come first.
+// Missing weights count as 1, options weighted 0 come last.
+func (s *State) randomOrder(n int, weights []float64) []int {
+	if weights == nil {
+		return s.rand.Perm(n)
+	}
+
+	order := make([]int, n)
+	keys := make([]float64, n)
+	for i := range order {
+		order[i] = i
+		w := 1.0
+		if i < len(weights) {
+			w = weights[i]
+		}
+		if w > 0 {
+			keys[i] = s.rand.ExpFloat64() / w
+		} else {
+			keys[i] = math.Inf(1)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return keys[order[i]] < keys[order[j]]
+	})
+	return order
+}
+
+// NewState creates a state, debug traces to stdout as WithDebug.
+func NewState(debug bool, limit int, chars []rune, seed int64) *State {
 	r := rand.New(rand.NewSource(seed))
 
 	if chars == nil {
 		chars = []rune(printableCharsNoNL)
 	}
 
-	return &state{
-		debug:    debug,
-		rand:     r,
-		limit:    limit,
-		chars:    chars,
-		boundary: defaultBoundary,
+	var tracer Tracer
+	if debug {
+		tracer = NewWriterTracer(os.Stdout)
+	}
+
+	return &State{
+		tracer: tracer,
+		rand:   r,
+		limit:  limit,
+		chars:  chars,
+
+		lineEnding: []rune("\n"),
+
+		attempts:   defaultAttempts,
+		backtracks: defaultBacktracks,
 	}
 }
@@ -0,0 +1,136 @@
+package regexp2gen
+
+import (
+	"fmt"
+	"io"
+)
+
+// EventKind is what happened in a trace Event.
+type EventKind int
+
+const (
+	// EventPattern is the pattern about to be generated, in Text.
+	EventPattern EventKind = iota
+	// EventCode is an instruction of the compiled code, described in Text.
+	EventCode
+	// EventAttempt is the start of an attempt.
+	EventAttempt
+	// EventOp is an instruction about to run, described in Text.
+	EventOp
+	// EventChoice is a random choice of an instruction, Choice of Choices.
+	EventChoice
+	// EventWrite is the runes an instruction wrote, in Text.
+	EventWrite
+	// EventMark is a mark at Pos pushed or popped by an instruction.
+	EventMark
+	// EventBacktrack is a failure going back to a choice point, which resumes at Index.
+	EventBacktrack
+	// EventVerify is the check of the generated Text by regexp2.
+	EventVerify
+	// EventResult is the generated Text.
+	EventResult
+)
+
+// Event is a step of the generation passed to a Tracer.
+type Event struct {
+	Kind EventKind
+	// opcode index, -1 when the event is not about an instruction
+	Index int
+	Text  string
+
+	Attempt int
+	Choice  int
+	Choices int
+
+	Pos  int
+	Push bool
+
+	Matched bool
+}
+
+func (e Event) String() string {
+	switch e.Kind {
+	case EventPattern:
+		return fmt.Sprintf("pattern %s", e.Text)
+	case EventCode:
+		return fmt.Sprintf("code %s", e.Text)
+	case EventAttempt:
+		return fmt.Sprintf("attempt %d", e.Attempt)
+	case EventOp:
+		return fmt.Sprintf("op %s", e.Text)
+	case EventChoice:
+		return fmt.Sprintf("choice %06d %d of %d", e.Index, e.Choice, e.Choices)
+	case EventWrite:
+		return fmt.Sprintf("write %06d %q", e.Index, e.Text)
+	case EventMark:
+		if e.Push {
+			return fmt.Sprintf("mark %06d push %d", e.Index, e.Pos)
+		}
+		return fmt.Sprintf("mark %06d pop %d", e.Index, e.Pos)
+	case EventBacktrack:
+		return fmt.Sprintf("backtrack to %06d", e.Index)
+	case EventVerify:
+		if e.Matched {
+			return fmt.Sprintf("verify %q matched", e.Text)
+		}
+		return fmt.Sprintf("verify %q not matched", e.Text)
+	case EventResult:
+		return fmt.Sprintf("result %q", e.Text)
+	}
+	return fmt.Sprintf("event %d", e.Kind)
+}
+
+// Tracer receives the events of a generation, see WithTracer.
+type Tracer interface {
+	Trace(e Event)
+}
+
+// TracerFunc is a function used as a Tracer.
+type TracerFunc func(e Event)
+
+func (f TracerFunc) Trace(e Event) {
+	f(e)
+}
+
+// NewWriterTracer returns a Tracer writing every event as a line to w.
+func NewWriterTracer(w io.Writer) Tracer {
+	return TracerFunc(func(e Event) {
+		fmt.Fprintln(w, e)
+	})
+}
+
+// trace passes e to the tracer of the runner, sub matchers have none.
+func (r *runner) trace(e Event) {
+	if r.tracer != nil {
+		r.tracer.Trace(e)
+	}
+}
+
+// traceMark traces the latest mark, after a push or before a pop.
+func (r *runner) traceMark(index int, push bool) {
+	if r.tracer == nil {
+		return
+	}
+	marks := r.m.buf.marks
+	if len(marks) == 0 {
+		return
+	}
+	r.trace(Event{Kind: EventMark, Index: index, Pos: marks[len(marks)-1], Push: push})
+}
+
+// traceWrite traces the runes written between the cursor positions from and to.
+func (r *runner) traceWrite(index, from, to int) {
+	if r.tracer == nil || from == to {
+		return
+	}
+	if to < from {
+		from, to = to, from
+	}
+	text := []rune{}
+	for i := from; i < to; i++ {
+		if ch, ok := r.m.buf.At(i); ok {
+			text = append(text, ch)
+		}
+	}
+	r.trace(Event{Kind: EventWrite, Index: index, Text: string(text)})
+}
@@ -1,23 +1,36 @@
 package regexp2gen
 
-import (
-	"errors"
+func containsRune(chars []rune, r rune) bool {
+	for _, c := range chars {
+		if c == r {
+			return true
+		}
+	}
+	return false
+}
 
-	"github.com/dlclark/regexp2/syntax"
-)
+func removeRune(chars []rune, r rune) []rune {
+	result := make([]rune, 0, len(chars))
+	for _, c := range chars {
+		if c != r {
+			result = append(result, c)
+		}
+	}
+	return result
+}
 
-// 尝试寻找一个能满足的匹配项
-// TODO：因为 charSet 没有提供相应的属性或者方法出来，先简单搞一下
-func resolveCharSet(set *syntax.CharSet) (r rune, err error) {
-	defer func() {
-		if e := recover(); e != nil {
-			err = errors.New("cat get char set")
+func containsString(strs []string, s string) bool {
+	for _, c := range strs {
+		if c == s {
+			return true
 		}
-	}()
-	if set.IsNegated() {
-		return r, errors.New("cat get char set")
 	}
+	return false
+}
 
-	r = set.SingletonChar()
-	return r, nil
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
 }